@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveFormat    string
+	archiveOlderThan string
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Manage branches archived with --archive",
+}
+
+var archiveListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List archived branches",
+	Args:  cobra.NoArgs,
+	RunE:  runArchiveList,
+}
+
+var archiveRestoreCmd = &cobra.Command{
+	Use:   "restore <branch|ref>...",
+	Short: "Recreate branches from their most recent archive",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runArchiveRestore,
+}
+
+var archivePurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete archives older than --older-than",
+	Args:  cobra.NoArgs,
+	RunE:  runArchivePurge,
+}
+
+func init() {
+	archiveListCmd.Flags().StringVar(&archiveFormat, "format", "table", "Output format: table or json")
+	archivePurgeCmd.Flags().StringVar(&archiveOlderThan, "older-than", "", "Purge archives older than this age, e.g. 90d or 12h")
+	_ = archivePurgeCmd.MarkFlagRequired("older-than")
+
+	archiveCmd.AddCommand(archiveListCmd, archiveRestoreCmd, archivePurgeCmd)
+	rootCmd.AddCommand(archiveCmd)
+}
+
+func runArchiveList(cmd *cobra.Command, args []string) error {
+	if archiveFormat != "table" && archiveFormat != "json" {
+		return fmt.Errorf("invalid format: %s (must be 'table' or 'json')", archiveFormat)
+	}
+
+	// Listing only reads, so it runs alongside a cleanup holding the lock
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	archives, err := git.ListArchives()
+	if err != nil {
+		return err
+	}
+
+	if archiveFormat == "json" {
+		if archives == nil {
+			archives = []internal.ArchivedBranch{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(archives)
+	}
+	internal.PrintArchives(os.Stdout, archives)
+	return nil
+}
+
+func runArchiveRestore(cmd *cobra.Command, args []string) error {
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+	lock, err := lockRepo(git)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock)
+	audit, err := startAudit(git, userConfig)
+	if err != nil {
+		return err
+	}
+	defer closeAudit(audit)
+
+	var failed bool
+	for _, name := range args {
+		archive, err := git.RestoreArchive(name)
+		if err != nil {
+			archive.Name = name
+		}
+		if auditErr := audit.RecordAction(archive.Name, archive.Tip, internal.DecisionRestore, archive.Ref, outcome(err, internal.OutcomeRestored)); auditErr != nil {
+			return auditErr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ Restored branch %s from %s\n", archive.Name, archive.Ref)
+	}
+	if failed {
+		return fmt.Errorf("some branches could not be restored")
+	}
+	return nil
+}
+
+func runArchivePurge(cmd *cobra.Command, args []string) error {
+	age, err := internal.ParseAge(archiveOlderThan)
+	if err != nil {
+		return err
+	}
+
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+	lock, err := lockRepo(git)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock)
+
+	archives, err := git.ListArchives()
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-age)
+	var expired []internal.ArchivedBranch
+	var refs []string
+	for _, archive := range archives {
+		if archive.ArchivedAt.Before(cutoff) {
+			expired = append(expired, archive)
+			refs = append(refs, archive.Ref)
+		}
+	}
+	if len(expired) == 0 {
+		fmt.Println("No archives to purge")
+		return nil
+	}
+
+	if !force && !assumeYes && !internal.ConfirmAction(os.Stdout, internal.DecisionPurge, refs, dryRun) {
+		fmt.Println("Canceled")
+		return nil
+	}
+
+	audit, err := startAudit(git, userConfig)
+	if err != nil {
+		return err
+	}
+	defer closeAudit(audit)
+
+	if dryRun {
+		for _, archive := range expired {
+			fmt.Printf("[DRY RUN] Would purge %s\n", archive.Ref)
+			result := internal.DeletionResult{Outcome: internal.OutcomeWouldDelete}
+			if err := audit.RecordAction(archive.Name, archive.Tip, internal.DecisionPurge, archive.Ref, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var failed bool
+	for _, archive := range expired {
+		err := git.DeleteArchive(archive)
+		if auditErr := audit.RecordAction(archive.Name, archive.Tip, internal.DecisionPurge, archive.Ref, outcome(err, internal.OutcomeDeleted)); auditErr != nil {
+			return auditErr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ Purged %s\n", archive.Ref)
+	}
+	if failed {
+		return fmt.Errorf("some archives could not be purged")
+	}
+	return nil
+}
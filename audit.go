@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySince  string
+	historyBranch string
+	historyFormat string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the audit log of cleanup sessions",
+	Args:  cobra.NoArgs,
+	RunE:  runHistory,
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only show records since a date (2024-01-31) or age (30d)")
+	historyCmd.Flags().StringVar(&historyBranch, "branch", "", "Only show records for this branch")
+	historyCmd.Flags().StringVar(&historyFormat, "format", "table", "Output format: table or json (one record per line)")
+
+	rootCmd.AddCommand(historyCmd)
+}
+
+// auditPath returns the audit log of git: --audit-log, then the audit_log
+// setting of config, then the default in the repository
+func auditPath(git *internal.GitRepo, config *internal.Config) (string, error) {
+	if auditLogPath != "" {
+		return auditLogPath, nil
+	}
+	if config.AuditLog != "" {
+		return config.AuditLog, nil
+	}
+	return git.AuditLogPath()
+}
+
+// startAudit starts an audit session for git. Nothing may be deleted if the
+// session cannot be recorded.
+func startAudit(git *internal.GitRepo, config *internal.Config) (*internal.AuditLog, error) {
+	path, err := auditPath(git, config)
+	if err != nil {
+		return nil, err
+	}
+	return internal.StartAudit(path, git.Path(), config.Sources, dryRun)
+}
+
+// lockRepo takes the repository lock of git for the rest of the session, so
+// that two sessions never delete or record branches at the same time
+func lockRepo(git *internal.GitRepo) (*internal.Lock, error) {
+	path, err := git.LockPath()
+	if err != nil {
+		return nil, err
+	}
+	return internal.AcquireLock(path, lockWait)
+}
+
+func releaseLock(lock *internal.Lock) {
+	if err := lock.Release(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ %v\n", err)
+	}
+}
+
+func closeAudit(audit *internal.AuditLog) {
+	if err := audit.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ %v\n", err)
+	}
+}
+
+// recordBranches records decision for branches. An empty decision records why
+// each branch was not a candidate.
+func recordBranches(audit *internal.AuditLog, branches []internal.Branch, decision string) error {
+	for _, b := range branches {
+		d := decision
+		if d == "" {
+			d = internal.ExcludedDecision(b)
+		}
+		if err := audit.RecordBranch(b, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outcome returns the result of an action that succeeded with the given
+// outcome unless err is set
+func outcome(err error, success string) internal.DeletionResult {
+	if err != nil {
+		return internal.DeletionResult{Outcome: internal.OutcomeFailed, Error: err.Error()}
+	}
+	return internal.DeletionResult{Outcome: success}
+}
+
+// without returns the branches that are not in exclude
+func without(branches, exclude []internal.Branch) []internal.Branch {
+	excluded := make(map[string]bool, len(exclude))
+	for _, b := range exclude {
+		excluded[b.Name] = true
+	}
+	var rest []internal.Branch
+	for _, b := range branches {
+		if !excluded[b.Name] {
+			rest = append(rest, b)
+		}
+	}
+	return rest
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	if historyFormat != "table" && historyFormat != "json" {
+		return fmt.Errorf("invalid format: %s (must be 'table' or 'json')", historyFormat)
+	}
+	filter := internal.AuditFilter{Branch: historyBranch}
+	if historySince != "" {
+		since, err := internal.ParseSince(historySince, time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Since = since
+	}
+
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+	path, err := auditPath(git, userConfig)
+	if err != nil {
+		return err
+	}
+	records, err := internal.ReadAuditLog(path)
+	if err != nil {
+		return err
+	}
+	matched := internal.FilterAudit(records, filter)
+
+	if historyFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, rec := range matched {
+			if err := encoder.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	internal.PrintAuditHistory(os.Stdout, matched, internal.AuditUsers(records))
+	return nil
+}
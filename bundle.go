@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var importBundleCmd = &cobra.Command{
+	Use:   "import-bundle <bundle|session-dir>...",
+	Short: "Restore branches from bundles written with --bundle-dir",
+	Long: "Restore branches from bundles written with --bundle-dir. Each argument is a bundle file, " +
+		"or a session directory whose manifest lists the bundles to import. Existing branches are never overwritten.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: runImportBundle,
+}
+
+func init() {
+	rootCmd.AddCommand(importBundleCmd)
+}
+
+func runImportBundle(cmd *cobra.Command, args []string) error {
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+	lock, err := lockRepo(git)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock)
+	audit, err := startAudit(git, userConfig)
+	if err != nil {
+		return err
+	}
+	defer closeAudit(audit)
+
+	var failed bool
+	for _, arg := range args {
+		paths, err := bundlePaths(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			failed = true
+			continue
+		}
+		for _, path := range paths {
+			names, err := git.ImportBundle(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+				failed = true
+				if auditErr := audit.RecordAction("", "", internal.DecisionImport, path, outcome(err, "")); auditErr != nil {
+					return auditErr
+				}
+				continue
+			}
+			for _, name := range names {
+				fmt.Printf("✓ Restored branch %s from %s\n", name, path)
+				if err := audit.RecordAction(name, "", internal.DecisionImport, path, outcome(nil, internal.OutcomeRestored)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if failed {
+		return fmt.Errorf("some bundles could not be imported")
+	}
+	return nil
+}
+
+// bundlePaths returns the bundle files named by arg: arg itself, or the
+// bundles in the manifest of a session directory
+func bundlePaths(arg string) ([]string, error) {
+	info, err := os.Stat(arg)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{arg}, nil
+	}
+
+	manifest, err := internal.LoadBundleManifest(arg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", arg, err)
+	}
+	paths := make([]string, len(manifest.Bundles))
+	for i, entry := range manifest.Bundles {
+		paths[i] = filepath.Join(arg, entry.File)
+	}
+	return paths, nil
+}
@@ -0,0 +1,277 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ArchiveMode selects how archived branches are kept
+type ArchiveMode string
+
+const (
+	// ArchiveRef moves a branch to refs/archive/<date>/<name>
+	ArchiveRef ArchiveMode = "ref"
+	// ArchiveTag replaces a branch with the annotated tag archive/<name>
+	ArchiveTag ArchiveMode = "tag"
+)
+
+const (
+	archiveRefPrefix = "refs/archive/"
+	archiveTagPrefix = "refs/tags/archive/"
+	archiveDate      = "2006-01-02"
+)
+
+// ErrArchiveExists is returned when the archive ref for a branch is taken
+var ErrArchiveExists = errors.New("branch is already archived")
+
+// ErrArchiveNotFound is returned when no archive matches a branch
+var ErrArchiveNotFound = errors.New("no archive found")
+
+// ArchivedBranch is a branch kept under refs/archive or as an archive tag
+type ArchivedBranch struct {
+	Name       string      `json:"name"`
+	Ref        string      `json:"ref"`
+	Tip        string      `json:"tip"`
+	Mode       ArchiveMode `json:"mode"`
+	ArchivedAt time.Time   `json:"archived_at"`
+}
+
+// ParseArchiveMode validates an --archive value
+func ParseArchiveMode(s string) (ArchiveMode, error) {
+	switch mode := ArchiveMode(s); mode {
+	case ArchiveRef, ArchiveTag:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid archive mode: %s (must be 'ref' or 'tag')", s)
+}
+
+// ArchiveRefName returns the ref that branch name is archived to on the day of now
+func ArchiveRefName(name string, mode ArchiveMode, now time.Time) string {
+	if mode == ArchiveTag {
+		return archiveTagPrefix + name
+	}
+	return archiveRefPrefix + now.Format(archiveDate) + "/" + name
+}
+
+// ArchiveBranch moves a local branch out of refs/heads instead of deleting it,
+// and returns the archive ref. The same checks as DeleteBranch apply, including
+// expectedTip.
+func (g *GitRepo) ArchiveBranch(name, expectedTip string, mode ArchiveMode, now time.Time) (string, error) {
+	if err := g.checkDeletable(name); err != nil {
+		return "", err
+	}
+
+	branchRef, err := g.repo.Reference(plumbing.NewBranchReferenceName(name), true)
+	if err != nil {
+		return "", fmt.Errorf("failed to read branch %s: %w", name, err)
+	}
+	if expectedTip != "" && branchRef.Hash().String() != expectedTip {
+		return "", branchChanged(name, branchRef.Hash(), expectedTip)
+	}
+
+	refName := plumbing.ReferenceName(ArchiveRefName(name, mode, now))
+	if _, err := g.repo.Reference(refName, false); err == nil {
+		return "", fmt.Errorf("%w: %s", ErrArchiveExists, refName)
+	}
+
+	switch mode {
+	case ArchiveTag:
+		message := fmt.Sprintf("Archived branch %s\n\nBranch: %s\nTip: %s\nArchived-At: %s\n",
+			name, name, branchRef.Hash(), now.Format(time.RFC3339))
+		_, err = g.repo.CreateTag(strings.TrimPrefix(refName.String(), "refs/tags/"), branchRef.Hash(), &git.CreateTagOptions{
+			Tagger:  g.signature(now),
+			Message: message,
+		})
+	default:
+		err = g.repo.Storer.SetReference(plumbing.NewHashReference(refName, branchRef.Hash()))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", refName, err)
+	}
+
+	// Keep the branch, and drop its archive again, if it moved in the meantime
+	if err := g.backend.deleteBranch(g, name, branchRef.Hash().String()); err != nil {
+		_ = g.repo.Storer.RemoveReference(refName)
+		return "", err
+	}
+	return refName.String(), nil
+}
+
+// signature returns the configured user, as git would record it
+func (g *GitRepo) signature(now time.Time) *object.Signature {
+	sig := &object.Signature{Name: "branch-clean", Email: "branch-clean@localhost", When: now}
+	if cfg, err := g.repo.ConfigScoped(config.GlobalScope); err == nil {
+		if cfg.User.Name != "" {
+			sig.Name = cfg.User.Name
+		}
+		if cfg.User.Email != "" {
+			sig.Email = cfg.User.Email
+		}
+	}
+	return sig
+}
+
+// ListArchives returns the archived branches, oldest first
+func (g *GitRepo) ListArchives() ([]ArchivedBranch, error) {
+	refs, err := g.repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+
+	var archives []ArchivedBranch
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		name := ref.Name().String()
+		switch {
+		case strings.HasPrefix(name, archiveRefPrefix):
+			date, branch, ok := strings.Cut(strings.TrimPrefix(name, archiveRefPrefix), "/")
+			if !ok {
+				return nil
+			}
+			archivedAt, err := time.ParseInLocation(archiveDate, date, time.Local)
+			if err != nil {
+				return nil
+			}
+			archives = append(archives, ArchivedBranch{
+				Name: branch, Ref: name, Tip: ref.Hash().String(), Mode: ArchiveRef, ArchivedAt: archivedAt,
+			})
+		case strings.HasPrefix(name, archiveTagPrefix):
+			// Only tags written by ArchiveBranch are archives; other archive/*
+			// tags, lightweight or not, belong to the user
+			tag, err := g.repo.TagObject(ref.Hash())
+			if err != nil {
+				return nil
+			}
+			archivedAt, ok := parseArchiveTrailer(tag.Message, tag.Target.String())
+			if !ok {
+				return nil
+			}
+			archives = append(archives, ArchivedBranch{
+				Name: strings.TrimPrefix(name, archiveTagPrefix), Ref: name, Tip: tag.Target.String(), Mode: ArchiveTag, ArchivedAt: archivedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archives: %w", err)
+	}
+
+	sort.SliceStable(archives, func(i, j int) bool {
+		if !archives[i].ArchivedAt.Equal(archives[j].ArchivedAt) {
+			return archives[i].ArchivedAt.Before(archives[j].ArchivedAt)
+		}
+		return archives[i].Ref < archives[j].Ref
+	})
+	return archives, nil
+}
+
+// parseArchiveTrailer reads the Branch, Tip and Archived-At trailer of an
+// archive tag message, and reports whether it is present and matches tip
+func parseArchiveTrailer(message, tip string) (time.Time, bool) {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(message, "\n") {
+		if key, value, ok := strings.Cut(line, ": "); ok {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	if fields["Branch"] == "" || fields["Tip"] != tip {
+		return time.Time{}, false
+	}
+	archivedAt, err := time.Parse(time.RFC3339, fields["Archived-At"])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return archivedAt, true
+}
+
+// RestoreArchive recreates a branch from its archive and removes the archive.
+// name is a branch name, restoring its most recent archive, or an archive ref.
+func (g *GitRepo) RestoreArchive(name string) (ArchivedBranch, error) {
+	archives, err := g.ListArchives()
+	if err != nil {
+		return ArchivedBranch{}, err
+	}
+
+	// archives are sorted oldest first, so the last match is the most recent
+	var found *ArchivedBranch
+	for i := range archives {
+		if archives[i].Name == name || archives[i].Ref == name {
+			found = &archives[i]
+		}
+	}
+	if found == nil {
+		return ArchivedBranch{}, fmt.Errorf("%w for %s", ErrArchiveNotFound, name)
+	}
+
+	branchRef := plumbing.NewBranchReferenceName(found.Name)
+	if _, err := g.repo.Reference(branchRef, false); err == nil {
+		return ArchivedBranch{}, fmt.Errorf("cannot restore %s: branch %s already exists", found.Ref, found.Name)
+	}
+	if err := g.repo.Storer.SetReference(plumbing.NewHashReference(branchRef, plumbing.NewHash(found.Tip))); err != nil {
+		return ArchivedBranch{}, fmt.Errorf("failed to restore branch %s: %w", found.Name, err)
+	}
+	if err := g.DeleteArchive(*found); err != nil {
+		return ArchivedBranch{}, err
+	}
+	return *found, nil
+}
+
+// DeleteArchive removes an archive. Its commits are then only kept by git
+// until the next garbage collection.
+func (g *GitRepo) DeleteArchive(archive ArchivedBranch) error {
+	if err := g.repo.Storer.RemoveReference(plumbing.ReferenceName(archive.Ref)); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", archive.Ref, err)
+	}
+	return nil
+}
+
+// PushArchive pushes an archive ref to the configured remote
+func (g *GitRepo) PushArchive(ref string) error {
+	return g.backend.pushRef(g, ref)
+}
+
+// ParseAge parses an age such as 90d, 12h or 1h30m. Days are 24 hours.
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age: %s (use e.g. 90d or 12h)", s)
+	}
+	return d, nil
+}
+
+// PrintArchives writes archived branches as a table
+func PrintArchives(w io.Writer, archives []ArchivedBranch) {
+	if len(archives) == 0 {
+		fmt.Fprintln(w, "No archived branches")
+		return
+	}
+
+	nameWidth := len("Branch")
+	for _, a := range archives {
+		nameWidth = max(nameWidth, len(a.Name))
+	}
+	fmt.Fprintf(w, "\n%s %s %s %s\n", padRight("Branch", nameWidth), padRight("Archived", dateWidth), padRight("Tip", 8), "Ref")
+	fmt.Fprintln(w, strings.Repeat("-", nameWidth+dateWidth+8+len("Ref")+3))
+	for _, a := range archives {
+		fmt.Fprintf(w, "%s %s %s %s\n", padRight(a.Name, nameWidth), padRight(getDateString(a.ArchivedAt), dateWidth), a.Tip[:min(8, len(a.Tip))], a.Ref)
+	}
+}
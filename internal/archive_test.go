@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestArchiveBranch(t *testing.T) {
+	tests := []struct {
+		mode ArchiveMode
+		ref  string
+	}{
+		{ArchiveRef, "refs/archive/2024-03-01/feat/x"},
+		{ArchiveTag, "refs/tags/archive/feat/x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			tmpDir, repo := setupTestRepo(t)
+			head, _ := repo.Head()
+			branchRef := plumbing.NewBranchReferenceName("feat/x")
+			if err := repo.Storer.SetReference(plumbing.NewHashReference(branchRef, head.Hash())); err != nil {
+				t.Fatal(err)
+			}
+
+			gitRepo, err := NewGitRepo(tmpDir)
+			if err != nil {
+				t.Fatalf("NewGitRepo failed: %v", err)
+			}
+
+			now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+			ref, err := gitRepo.ArchiveBranch("feat/x", "", tt.mode, now)
+			if err != nil {
+				t.Fatalf("ArchiveBranch failed: %v", err)
+			}
+			if ref != tt.ref {
+				t.Errorf("ref = %s, want %s", ref, tt.ref)
+			}
+			if _, err := repo.Reference(branchRef, false); err == nil {
+				t.Error("branch still exists")
+			}
+
+			archives, err := gitRepo.ListArchives()
+			if err != nil {
+				t.Fatalf("ListArchives failed: %v", err)
+			}
+			if len(archives) != 1 {
+				t.Fatalf("expected 1 archive, got %d", len(archives))
+			}
+			a := archives[0]
+			if a.Name != "feat/x" || a.Ref != tt.ref || a.Tip != head.Hash().String() || a.Mode != tt.mode {
+				t.Errorf("unexpected archive: %+v", a)
+			}
+			if y, m, d := a.ArchivedAt.Date(); y != 2024 || m != 3 || d != 1 {
+				t.Errorf("ArchivedAt = %v, want 2024-03-01", a.ArchivedAt)
+			}
+
+			restored, err := gitRepo.RestoreArchive("feat/x")
+			if err != nil {
+				t.Fatalf("RestoreArchive failed: %v", err)
+			}
+			if restored.Ref != tt.ref {
+				t.Errorf("restored %s, want %s", restored.Ref, tt.ref)
+			}
+			ref2, err := repo.Reference(branchRef, false)
+			if err != nil || ref2.Hash() != head.Hash() {
+				t.Errorf("branch not restored to %s: %v", head.Hash(), err)
+			}
+			if archives, _ := gitRepo.ListArchives(); len(archives) != 0 {
+				t.Errorf("archive not removed: %+v", archives)
+			}
+		})
+	}
+}
+
+func TestArchiveBranch_Errors(t *testing.T) {
+	tmpDir, repo := setupTestRepo(t)
+	head, _ := repo.Head()
+	for _, name := range []string{"a", "b"} {
+		ref := plumbing.NewBranchReferenceName(name)
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(ref, head.Hash())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gitRepo, _ := NewGitRepo(tmpDir)
+	now := time.Now()
+
+	if _, err := gitRepo.ArchiveBranch("master", "", ArchiveRef, now); !errors.Is(err, ErrCurrentBranch) {
+		t.Errorf("expected ErrCurrentBranch, got %v", err)
+	}
+
+	if _, err := gitRepo.ArchiveBranch("a", "", ArchiveTag, now); err != nil {
+		t.Fatalf("ArchiveBranch failed: %v", err)
+	}
+	// Recreate the branch: its archive tag is taken
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("a"), head.Hash())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gitRepo.ArchiveBranch("a", "", ArchiveTag, now); !errors.Is(err, ErrArchiveExists) {
+		t.Errorf("expected ErrArchiveExists, got %v", err)
+	}
+	if _, err := gitRepo.RestoreArchive("a"); err == nil {
+		t.Error("expected an error restoring over an existing branch")
+	}
+	if _, err := gitRepo.RestoreArchive("b"); !errors.Is(err, ErrArchiveNotFound) {
+		t.Errorf("expected ErrArchiveNotFound, got %v", err)
+	}
+}
+
+func TestListArchives_IgnoresUserTags(t *testing.T) {
+	tmpDir, repo := setupTestRepo(t)
+	head, _ := repo.Head()
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/tags/archive/light", head.Hash())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("archive/annotated", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test", Email: "test@test.com", When: time.Now()},
+		Message: "Release archive",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	gitRepo, _ := NewGitRepo(tmpDir)
+	archives, err := gitRepo.ListArchives()
+	if err != nil {
+		t.Fatalf("ListArchives failed: %v", err)
+	}
+	if len(archives) != 0 {
+		t.Errorf("expected user tags to be ignored, got %+v", archives)
+	}
+}
+
+func TestPushArchive(t *testing.T) {
+	for _, backend := range []Backend{BackendExec, BackendGoGit} {
+		t.Run(string(backend), func(t *testing.T) {
+			localDir, _, remote := setupRemoteRepo(t, "feature")
+
+			gitRepo, _ := NewGitRepo(localDir)
+			if err := gitRepo.SetBackend(backend); err != nil {
+				t.Fatalf("SetBackend failed: %v", err)
+			}
+			ref, err := gitRepo.ArchiveBranch("feature", "", ArchiveRef, time.Now())
+			if err != nil {
+				t.Fatalf("ArchiveBranch failed: %v", err)
+			}
+			if err := gitRepo.PushArchive(ref); err != nil {
+				t.Fatalf("PushArchive failed: %v", err)
+			}
+			if _, err := remote.Reference(plumbing.ReferenceName(ref), false); err != nil {
+				t.Errorf("archive not pushed: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"90d", 90 * 24 * time.Hour, true},
+		{"0d", 0, true},
+		{"12h", 12 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"-1d", 0, false},
+		{"xd", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAge(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AuditFile is the name of the audit log in the repository's branch-clean directory
+const AuditFile = "audit.log"
+
+// Audit events. A session starts with AuditStart, records an AuditBranch event
+// for every branch it considered and ends with AuditEnd.
+const (
+	AuditStart  = "start"
+	AuditBranch = "branch"
+	AuditEnd    = "end"
+)
+
+// Decisions recorded for each branch considered
+const (
+	DecisionProtected    = "protected"
+	DecisionUnanalyzable = "unanalyzable"
+	DecisionKept         = "kept"
+	DecisionNotSelected  = "not-selected"
+	DecisionDeclined     = "declined"
+	DecisionDelete       = "delete"
+	DecisionArchive      = "archive"
+	DecisionRestore      = "restore"
+	DecisionPurge        = "purge"
+	DecisionImport       = "import"
+	DecisionPrune        = "prune"
+	// DecisionRefused is recorded by apply for planned branches that changed
+	// or became protected since planning
+	DecisionRefused = "refused"
+)
+
+// AuditRecord is one line of the audit log. Session fields are set on
+// AuditStart events, branch fields on AuditBranch events. Archive is the
+// archive ref or bundle file a branch was moved to or restored from.
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	Session    string    `json:"session"`
+	Event      string    `json:"event"`
+	Repository string    `json:"repository,omitempty"`
+
+	User          string   `json:"user,omitempty"`
+	Host          string   `json:"host,omitempty"`
+	Command       []string `json:"command,omitempty"`
+	ConfigSources []string `json:"config_sources,omitempty"`
+	DryRun        bool     `json:"dry_run,omitempty"`
+
+	Branch   string          `json:"branch,omitempty"`
+	Tip      string          `json:"tip,omitempty"`
+	Status   string          `json:"status,omitempty"`
+	Decision string          `json:"decision,omitempty"`
+	Archive  string          `json:"archive,omitempty"`
+	Local    *DeletionResult `json:"local,omitempty"`
+	Remote   *DeletionResult `json:"remote,omitempty"`
+}
+
+// AuditLog appends the records of one session to an audit log file
+type AuditLog struct {
+	file       *os.File
+	session    string
+	repository string
+	encoder    *json.Encoder
+}
+
+// AuditLogPath returns the default audit log of the repository, shared by all
+// of its worktrees
+func (g *GitRepo) AuditLogPath() (string, error) {
+	dir, err := g.dataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate audit log: %w", err)
+	}
+	return filepath.Join(dir, AuditFile), nil
+}
+
+// StartAudit opens the audit log at path for appending and records the start
+// of a session for the repository, with the user, host and command line
+func StartAudit(path, repository string, configSources []string, dryRun bool) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	log := &AuditLog{
+		file:       file,
+		session:    newSessionID(),
+		repository: repository,
+		encoder:    json.NewEncoder(file),
+	}
+	host, _ := os.Hostname()
+	err = log.Record(AuditRecord{
+		Event:         AuditStart,
+		User:          currentUser(),
+		Host:          host,
+		Command:       os.Args,
+		ConfigSources: configSources,
+		DryRun:        dryRun,
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return log, nil
+}
+
+// Record appends rec to the log, filling in the time, session and repository
+func (l *AuditLog) Record(rec AuditRecord) error {
+	rec.Time = time.Now()
+	rec.Session = l.session
+	rec.Repository = l.repository
+	if err := l.encoder.Encode(rec); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// RecordBranch records the decision taken for a branch
+func (l *AuditLog) RecordBranch(b Branch, decision string) error {
+	return l.Record(AuditRecord{Event: AuditBranch, Branch: b.Name, Tip: b.Tip, Status: b.Status(), Decision: decision})
+}
+
+// RecordResult records the decision and the outcome of cleaning up a branch
+func (l *AuditLog) RecordResult(b Branch, decision string, result CleanupResult) error {
+	local := result.Local
+	return l.Record(AuditRecord{
+		Event:    AuditBranch,
+		Branch:   b.Name,
+		Tip:      b.Tip,
+		Status:   b.Status(),
+		Decision: decision,
+		Archive:  result.Archive,
+		Local:    &local,
+		Remote:   result.Remote,
+	})
+}
+
+// RecordAction records an action on an archived or bundled branch, such as
+// restoring it, and its outcome
+func (l *AuditLog) RecordAction(branch, tip, decision, archive string, local DeletionResult) error {
+	return l.Record(AuditRecord{Event: AuditBranch, Branch: branch, Tip: tip, Decision: decision, Archive: archive, Local: &local})
+}
+
+// Close records the end of the session and closes the log
+func (l *AuditLog) Close() error {
+	err := l.Record(AuditRecord{Event: AuditEnd})
+	if closeErr := l.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close audit log: %w", closeErr)
+	}
+	return err
+}
+
+// ExcludedDecision returns why FilterBranches left b out of the candidates
+func ExcludedDecision(b Branch) string {
+	switch {
+	case b.Protected:
+		return DecisionProtected
+	case b.Error != "":
+		return DecisionUnanalyzable
+	}
+	return DecisionKept
+}
+
+func newSessionID() string {
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(buf)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// AuditFilter selects audit records. Zero fields match everything; a branch
+// filter only matches AuditBranch events.
+type AuditFilter struct {
+	Since  time.Time
+	Branch string
+}
+
+// FilterAudit returns the records that match filter
+func FilterAudit(records []AuditRecord, filter AuditFilter) []AuditRecord {
+	var matched []AuditRecord
+	for _, rec := range records {
+		if !filter.Since.IsZero() && rec.Time.Before(filter.Since) {
+			continue
+		}
+		if filter.Branch != "" && (rec.Event != AuditBranch || rec.Branch != filter.Branch) {
+			continue
+		}
+		matched = append(matched, rec)
+	}
+	return matched
+}
+
+// ReadAuditLog returns the records of the audit log at path. Lines that cannot
+// be parsed, such as a line cut short by a crash, are skipped. A missing log
+// has no records.
+func ReadAuditLog(path string) ([]AuditRecord, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// ParseSince parses a --since value: a date (2006-01-02), an RFC 3339 time or
+// an age such as 30d relative to now
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	age, err := ParseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %s (use a date like 2024-01-31 or an age like 30d)", s)
+	}
+	return now.Add(-age), nil
+}
+
+// PrintAuditHistory writes the branch events of records as a table. The user
+// of each event is taken from the start of its session when present.
+func PrintAuditHistory(w io.Writer, records []AuditRecord, users map[string]string) {
+	var rows [][]string
+	for _, rec := range records {
+		if rec.Event != AuditBranch {
+			continue
+		}
+		outcome := func(r *DeletionResult) string {
+			if r == nil {
+				return "-"
+			}
+			return r.Outcome
+		}
+		rows = append(rows, []string{
+			rec.Time.Local().Format("2006-01-02 15:04"),
+			users[rec.Session],
+			rec.Branch,
+			rec.Decision,
+			outcome(rec.Local),
+			outcome(rec.Remote),
+		})
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "No matching audit records")
+		return
+	}
+
+	header := []string{"Time", "User", "Branch", "Decision", "Local", "Remote"}
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	writeRow := func(row []string) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = padRight(cell, widths[i])
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, " "), " "))
+	}
+
+	fmt.Fprintln(w)
+	writeRow(header)
+	total := len(widths) - 1
+	for _, width := range widths {
+		total += width
+	}
+	fmt.Fprintln(w, strings.Repeat("-", total))
+	for _, row := range rows {
+		writeRow(row)
+	}
+}
+
+// AuditUsers maps the sessions of records to the user that started them
+func AuditUsers(records []AuditRecord) map[string]string {
+	users := make(map[string]string)
+	for _, rec := range records {
+		if rec.Event == AuditStart {
+			users[rec.Session] = rec.User
+		}
+	}
+	return users
+}
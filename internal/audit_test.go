@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "branch-clean", AuditFile)
+
+	for i := 0; i < 2; i++ {
+		log, err := StartAudit(path, "/repo", []string{"/home/me/.branch-clean.yaml"}, false)
+		if err != nil {
+			t.Fatalf("StartAudit failed: %v", err)
+		}
+		if err := log.RecordBranch(Branch{Name: "main", Protected: true}, DecisionProtected); err != nil {
+			t.Fatalf("RecordBranch failed: %v", err)
+		}
+		result := CleanupResult{
+			Branch: "feature",
+			Local:  DeletionResult{Outcome: OutcomeDeleted},
+			Remote: &DeletionResult{Outcome: OutcomeFailed, Error: "rejected"},
+		}
+		if err := log.RecordResult(Branch{Name: "feature", Tip: "abc", IsMerged: true}, DecisionDelete, result); err != nil {
+			t.Fatalf("RecordResult failed: %v", err)
+		}
+		if err := log.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	// A torn last line is skipped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-`)
+	f.Close()
+
+	records, err := ReadAuditLog(path)
+	if err != nil {
+		t.Fatalf("ReadAuditLog failed: %v", err)
+	}
+	if len(records) != 8 {
+		t.Fatalf("expected 8 records, got %d", len(records))
+	}
+
+	start, branch := records[0], records[2]
+	if start.Event != AuditStart || start.User == "" || len(start.Command) == 0 || start.ConfigSources[0] != "/home/me/.branch-clean.yaml" {
+		t.Errorf("unexpected start record: %+v", start)
+	}
+	if branch.Session != start.Session || branch.Repository != "/repo" || branch.Status != "merged" ||
+		branch.Local.Outcome != OutcomeDeleted || branch.Remote.Error != "rejected" {
+		t.Errorf("unexpected branch record: %+v", branch)
+	}
+	if records[3].Event != AuditEnd || records[4].Session == start.Session {
+		t.Error("expected a new session after the end of the first one")
+	}
+
+	matched := FilterAudit(records, AuditFilter{Branch: "feature"})
+	if len(matched) != 2 || matched[0].Decision != DecisionDelete {
+		t.Errorf("unexpected branch filter result: %+v", matched)
+	}
+	if matched := FilterAudit(records, AuditFilter{Since: time.Now().Add(time.Hour)}); len(matched) != 0 {
+		t.Errorf("expected no records in the future, got %d", len(matched))
+	}
+
+	var buf bytes.Buffer
+	PrintAuditHistory(&buf, matched, AuditUsers(records))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[2], start.User) || !strings.Contains(lines[2], "deleted") {
+		t.Errorf("unexpected history:\n%s", buf.String())
+	}
+}
+
+func TestReadAuditLog_Missing(t *testing.T) {
+	records, err := ReadAuditLog(filepath.Join(t.TempDir(), AuditFile))
+	if err != nil || records != nil {
+		t.Errorf("expected no records, got %v, %v", records, err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	if got, err := ParseSince("7d", now); err != nil || !got.Equal(now.AddDate(0, 0, -7)) {
+		t.Errorf("ParseSince(7d) = %v, %v", got, err)
+	}
+	if got, err := ParseSince("2024-03-01", now); err != nil || got.Day() != 1 {
+		t.Errorf("ParseSince(2024-03-01) = %v, %v", got, err)
+	}
+	if got, err := ParseSince("2024-03-01T10:00:00Z", now); err != nil || got.Hour() != 10 {
+		t.Errorf("ParseSince(RFC 3339) = %v, %v", got, err)
+	}
+	if _, err := ParseSince("last week", now); err == nil {
+		t.Error("expected an error for an invalid time")
+	}
+}
+
+func TestExcludedDecision(t *testing.T) {
+	tests := []struct {
+		branch Branch
+		want   string
+	}{
+		{Branch{Protected: true, IsMerged: true}, DecisionProtected},
+		{Branch{Error: "bad object"}, DecisionUnanalyzable},
+		{Branch{}, DecisionKept},
+	}
+	for _, tt := range tests {
+		if got := ExcludedDecision(tt.branch); got != tt.want {
+			t.Errorf("ExcludedDecision(%+v) = %s, want %s", tt.branch, got, tt.want)
+		}
+	}
+}
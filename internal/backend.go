@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Backend selects how merge detection and remote branch deletion are performed
+type Backend string
+
+const (
+	// BackendAuto uses the git binary when it is on PATH and go-git otherwise
+	BackendAuto Backend = "auto"
+	// BackendExec shells out to the git binary
+	BackendExec Backend = "exec"
+	// BackendGoGit runs in-process using go-git and needs no git binary
+	BackendGoGit Backend = "go-git"
+)
+
+// gitBackend implements the repository operations that can either run the git
+// binary or be performed in-process
+type gitBackend interface {
+	// mergedBranches returns the set of local branches merged into the default
+	// branch. If part of the history is missing it returns the branches found
+	// merged so far together with errIncompleteHistory.
+	mergedBranches(g *GitRepo) (map[string]bool, error)
+	// deleteBranch deletes a local branch if it points at expectedTip, or
+	// unconditionally if expectedTip is empty
+	deleteBranch(g *GitRepo, name, expectedTip string) error
+	// deleteRemoteBranches deletes branches from the configured remote in one
+	// push and returns the result of each. An error means the push itself
+	// failed and no result is known.
+	deleteRemoteBranches(g *GitRepo, deletions []RemoteDeletion) ([]error, error)
+	// remoteBranchTips lists the branches on the configured remote
+	remoteBranchTips(g *GitRepo) (map[string]string, error)
+	// isAncestor reports whether commit is in the history of branch
+	isAncestor(g *GitRepo, commit, branch string) (bool, error)
+	// pushRef pushes a ref to the same name on the configured remote
+	pushRef(g *GitRepo, ref string) error
+}
+
+// newBackend resolves name to a backend implementation
+func newBackend(name Backend) (gitBackend, error) {
+	switch name {
+	case BackendAuto, "":
+		if _, err := exec.LookPath("git"); err == nil {
+			return execBackend{}, nil
+		}
+		return goGitBackend{}, nil
+	case BackendExec:
+		if _, err := exec.LookPath("git"); err != nil {
+			return nil, fmt.Errorf("the exec backend requires git on PATH: %w\nUse --backend go-git to run without the git binary", err)
+		}
+		return execBackend{}, nil
+	case BackendGoGit:
+		return goGitBackend{}, nil
+	}
+	return nil, fmt.Errorf("invalid backend: %s (must be 'auto', 'exec' or 'go-git')", name)
+}
+
+// execBackend runs the git binary in the repository directory
+type execBackend struct{}
+
+// mergedBranches uses a single `git for-each-ref --merged` call to check every
+// branch at once, so the cost no longer grows with one git process per branch.
+// Like `git merge-base --is-ancestor` this handles all merge strategies that keep
+// the branch tip in the default branch history.
+func (execBackend) mergedBranches(g *GitRepo) (map[string]bool, error) {
+	output, err := g.runGit("for-each-ref", "--merged="+g.defaultBranch, "--format=%(refname)", "refs/heads/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check merge status: %w", err)
+	}
+
+	merged := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		name := strings.TrimPrefix(strings.TrimSpace(line), "refs/heads/")
+		if name != "" {
+			merged[name] = true
+		}
+	}
+	return merged, nil
+}
+
+// deleteBranch uses git update-ref, which checks the old value and removes the
+// ref under git's own lock
+func (execBackend) deleteBranch(g *GitRepo, name, expectedTip string) error {
+	args := []string{"update-ref", "-d", plumbing.NewBranchReferenceName(name).String()}
+	if expectedTip != "" {
+		args = append(args, expectedTip)
+	}
+	if _, err := g.runGit(args...); err != nil {
+		if expectedTip != "" {
+			if tipErr := g.checkTip(name, expectedTip); errors.Is(tipErr, ErrBranchChanged) {
+				return tipErr
+			}
+		}
+		return fmt.Errorf("failed to delete branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// deleteRemoteBranches deletes all branches in one git push, with a
+// --force-with-lease expectation for each, and reads the result of every ref
+// from the porcelain output
+func (execBackend) deleteRemoteBranches(g *GitRepo, deletions []RemoteDeletion) ([]error, error) {
+	args := []string{"push", "--porcelain"}
+	if g.atomicPush {
+		args = append(args, "--atomic")
+	}
+	var refspecs []string
+	for _, d := range deletions {
+		ref := plumbing.NewBranchReferenceName(d.Name).String()
+		if d.ExpectedTip != "" {
+			args = append(args, "--force-with-lease="+ref+":"+d.ExpectedTip)
+		}
+		refspecs = append(refspecs, ":"+ref)
+	}
+
+	cmd := g.gitCommand(append(append(args, g.remote), refspecs...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	// A rejected ref fails the push, but is still reported with the others
+	if results, ok := parsePushPorcelain(stdout.String(), deletions); ok {
+		return results, nil
+	}
+	if err == nil {
+		err = errors.New("git push did not report every branch")
+	}
+	return nil, fmt.Errorf("%w\nOutput: %s", err, strings.TrimSpace(stderr.String()))
+}
+
+func (execBackend) remoteBranchTips(g *GitRepo) (map[string]string, error) {
+	output, err := g.runGit("ls-remote", "--heads", g.remote)
+	if err != nil {
+		return nil, err
+	}
+
+	tips := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		hash, ref, found := strings.Cut(strings.TrimSpace(line), "\t")
+		if found && strings.HasPrefix(ref, "refs/heads/") {
+			tips[strings.TrimPrefix(ref, "refs/heads/")] = hash
+		}
+	}
+	return tips, nil
+}
+
+func (execBackend) isAncestor(g *GitRepo, commit, branch string) (bool, error) {
+	err := g.gitCommand("merge-base", "--is-ancestor", commit, plumbing.NewBranchReferenceName(branch).String()).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (execBackend) pushRef(g *GitRepo, ref string) error {
+	output, err := g.gitCommand("push", g.remote, ref+":"+ref).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to push %s: %w\nOutput: %s", ref, err, output)
+	}
+	return nil
+}
+
+// runGit runs git with args in the repository and returns its standard output.
+// On failure the error includes git's standard error.
+func (g *GitRepo) runGit(args ...string) (string, error) {
+	cmd := g.gitCommand(args...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("%w\nOutput: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// gitCommand returns a git command that operates on this repository. The git
+// directory is passed explicitly, so git finds the same repository that
+// branch-clean discovered, whatever GIT_DIR is set to.
+func (g *GitRepo) gitCommand(args ...string) *exec.Cmd {
+	if gitDir, err := g.gitDir(); err == nil {
+		args = append([]string{"--git-dir=" + gitDir}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.repoPath
+	return cmd
+}
@@ -0,0 +1,255 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	commitgraphfmt "github.com/go-git/go-git/v5/plumbing/format/commitgraph/v2"
+	"github.com/go-git/go-git/v5/plumbing/object/commitgraph"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// goGitBackend performs all operations in-process with go-git
+type goGitBackend struct{}
+
+// mergedBranches walks the default branch history once and marks every branch
+// whose tip is found in it. When the repository has a commit-graph file, its
+// generation numbers prune the walk: a commit can only be an ancestor of
+// commits with a higher generation, so nothing below the lowest branch tip
+// generation needs to be visited. Missing history is skipped and reported as
+// errIncompleteHistory along with the branches found merged so far.
+func (goGitBackend) mergedBranches(g *GitRepo) (map[string]bool, error) {
+	target, err := g.repo.ResolveRevision(plumbing.Revision(g.defaultBranch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve default branch %s: %w", g.defaultBranch, err)
+	}
+
+	index, closeIndex := g.commitNodeIndex()
+	defer closeIndex()
+
+	branchRefs, err := g.repo.Branches()
+	if err != nil {
+		return nil, err
+	}
+
+	tips := make(map[plumbing.Hash][]string)
+	err = branchRefs.ForEach(func(ref *plumbing.Reference) error {
+		tips[ref.Hash()] = append(tips[ref.Hash()], ref.Name().Short())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Generation 0 means the commit-graph was written without generation
+	// numbers, in which case the walk cannot be pruned
+	minGeneration := uint64(math.MaxUint64)
+	prune := true
+	for hash := range tips {
+		node, nodeErr := index.Get(hash)
+		if nodeErr != nil {
+			// Missing tip objects are reported by the branch analysis itself
+			continue
+		}
+		if node.Generation() == 0 {
+			prune = false
+		}
+		minGeneration = min(minGeneration, node.Generation())
+	}
+
+	merged := make(map[string]bool)
+	incomplete := false
+	remaining := len(tips)
+	visited := make(map[plumbing.Hash]bool)
+	stack := []plumbing.Hash{*target}
+
+	for len(stack) > 0 && remaining > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[hash] {
+			continue
+		}
+		visited[hash] = true
+
+		node, nodeErr := index.Get(hash)
+		if errors.Is(nodeErr, plumbing.ErrObjectNotFound) {
+			// Beyond a shallow boundary or not fetched in a partial clone;
+			// keep walking the history that is present
+			incomplete = true
+			continue
+		}
+		if nodeErr != nil {
+			return nil, fmt.Errorf("failed to check merge status: commit %s: %w", hash, nodeErr)
+		}
+		if prune && node.Generation() != 0 && node.Generation() < minGeneration {
+			continue
+		}
+
+		if names, ok := tips[hash]; ok {
+			for _, name := range names {
+				merged[name] = true
+			}
+			remaining--
+		}
+
+		for _, parent := range node.ParentHashes() {
+			if !visited[parent] {
+				stack = append(stack, parent)
+			}
+		}
+	}
+
+	if incomplete && remaining > 0 {
+		return merged, errIncompleteHistory
+	}
+	return merged, nil
+}
+
+// commitNodeIndex returns a commit node index backed by the repository's
+// commit-graph when one exists, and by plain object storage otherwise.
+// The returned function releases the commit-graph file.
+func (g *GitRepo) commitNodeIndex() (commitgraph.CommitNodeIndex, func()) {
+	if storage, ok := g.repo.Storer.(*filesystem.Storage); ok {
+		if graph, err := commitgraphfmt.OpenChainOrFileIndex(storage.Filesystem()); err == nil {
+			return commitgraph.NewGraphCommitNodeIndex(graph, g.repo.Storer), func() { _ = graph.Close() }
+		}
+	}
+	return commitgraph.NewObjectCommitNodeIndex(g.repo.Storer), func() {}
+}
+
+// deleteBranch checks the tip before removing the ref. go-git has no
+// conditional delete, so unlike the exec backend a commit made in the short
+// window between the two steps is not detected.
+func (goGitBackend) deleteBranch(g *GitRepo, name, expectedTip string) error {
+	if expectedTip != "" {
+		if err := g.checkTip(name, expectedTip); err != nil {
+			return err
+		}
+	}
+	if err := g.repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(name)); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// deleteRemoteBranches lists the remote's refs once to check each expected
+// tip, then pushes empty refspecs for the remaining branches in one push. go-git
+// ignores ForceWithLease for deletions, so RequireRemoteRefs guards against the
+// remote moving in between. go-git only reports the first rejected ref, so a
+// rejected push fails every branch in it.
+func (goGitBackend) deleteRemoteBranches(g *GitRepo, deletions []RemoteDeletion) ([]error, error) {
+	remote, err := g.repo.Remote(g.remote)
+	if err != nil {
+		return nil, err
+	}
+	advertised, err := remote.List(&git.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	tips := make(map[plumbing.ReferenceName]string, len(advertised))
+	for _, ref := range advertised {
+		tips[ref.Name()] = ref.Hash().String()
+	}
+
+	results := make([]error, len(deletions))
+	options := &git.PushOptions{RemoteName: g.remote, Atomic: g.atomicPush}
+	var pushed []int
+	var rejected bool
+	for i, d := range deletions {
+		refName := plumbing.NewBranchReferenceName(d.Name)
+		tip, exists := tips[refName]
+		switch {
+		case d.ExpectedTip != "" && tip != d.ExpectedTip:
+			results[i] = remoteBranchChanged(d)
+			rejected = true
+		case !exists:
+			results[i] = remoteBranchNotFound(d)
+		default:
+			options.RefSpecs = append(options.RefSpecs, config.RefSpec(":"+refName.String()))
+			if d.ExpectedTip != "" {
+				options.RequireRemoteRefs = append(options.RequireRemoteRefs, config.RefSpec(d.ExpectedTip+":"+refName.String()))
+			}
+			pushed = append(pushed, i)
+		}
+	}
+
+	switch {
+	case len(pushed) == 0:
+	case rejected && g.atomicPush:
+		for _, i := range pushed {
+			results[i] = errors.New("failed to delete remote branch: atomic push failed")
+		}
+	default:
+		err := g.repo.Push(options)
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			if isTransientPushError(err) {
+				return nil, err
+			}
+			for _, i := range pushed {
+				results[i] = fmt.Errorf("failed to delete remote branch: %w", err)
+			}
+		}
+	}
+
+	// Like git push --delete, drop the now stale remote-tracking refs
+	for i, d := range deletions {
+		if results[i] != nil {
+			continue
+		}
+		trackingRef := plumbing.NewRemoteReferenceName(g.remote, d.Name)
+		if err := g.repo.Storer.RemoveReference(trackingRef); err != nil {
+			results[i] = fmt.Errorf("failed to remove remote-tracking branch: %w", err)
+		}
+	}
+	return results, nil
+}
+
+func (goGitBackend) remoteBranchTips(g *GitRepo) (map[string]string, error) {
+	remote, err := g.repo.Remote(g.remote)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := remote.List(&git.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	tips := make(map[string]string)
+	for _, ref := range refs {
+		if ref.Name().IsBranch() && ref.Type() == plumbing.HashReference {
+			tips[ref.Name().Short()] = ref.Hash().String()
+		}
+	}
+	return tips, nil
+}
+
+func (goGitBackend) isAncestor(g *GitRepo, commit, branch string) (bool, error) {
+	c, err := g.repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return false, err
+	}
+	ref, err := g.repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return false, err
+	}
+	target, err := g.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return false, err
+	}
+	return c.IsAncestor(target)
+}
+
+func (goGitBackend) pushRef(g *GitRepo, ref string) error {
+	err := g.repo.Push(&git.PushOptions{
+		RemoteName: g.remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(ref + ":" + ref)},
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to push %s: %w", ref, err)
+	}
+	return nil
+}
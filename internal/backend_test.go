@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+func requireGit(tb testing.TB) {
+	tb.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		tb.Skip("git binary not available")
+	}
+}
+
+// setupRemoteRepo creates a bare "origin" repository and a clone of it with the
+// given branches pushed to origin
+func setupRemoteRepo(t *testing.T, branches ...string) (string, *git.Repository, *git.Repository) {
+	t.Helper()
+	requireGit(t)
+
+	localDir, local := setupTestRepo(t)
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatalf("failed to init remote: %v", err)
+	}
+
+	if _, err := local.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}}); err != nil {
+		t.Fatalf("failed to add remote: %v", err)
+	}
+
+	head, _ := local.Head()
+	refSpecs := []config.RefSpec{"refs/heads/master:refs/heads/master"}
+	for _, name := range branches {
+		ref := plumbing.NewBranchReferenceName(name)
+		if err := local.Storer.SetReference(plumbing.NewHashReference(ref, head.Hash())); err != nil {
+			t.Fatalf("failed to create branch: %v", err)
+		}
+		refSpecs = append(refSpecs, config.RefSpec(ref+":"+ref))
+	}
+	if err := local.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: refSpecs}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+	if err := local.Fetch(&git.FetchOptions{RemoteName: "origin"}); err != nil && err != git.NoErrAlreadyUpToDate {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+
+	return localDir, local, remote
+}
+
+func TestNewBackend(t *testing.T) {
+	if _, err := newBackend("svn"); err == nil {
+		t.Error("expected error for invalid backend")
+	}
+
+	b, err := newBackend(BackendGoGit)
+	if err != nil {
+		t.Fatalf("newBackend failed: %v", err)
+	}
+	if _, ok := b.(goGitBackend); !ok {
+		t.Errorf("expected goGitBackend, got %T", b)
+	}
+}
+
+func TestGoGitBackend_MergedBranches(t *testing.T) {
+	requireGit(t)
+	dir := setupSyntheticRepo(t, 30, 40)
+
+	gitRepo, err := NewGitRepo(dir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+
+	want, err := execBackend{}.mergedBranches(gitRepo)
+	if err != nil {
+		t.Fatalf("exec mergedBranches failed: %v", err)
+	}
+
+	t.Run("object storage", func(t *testing.T) {
+		got, err := goGitBackend{}.mergedBranches(gitRepo)
+		if err != nil {
+			t.Fatalf("go-git mergedBranches failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("go-git backend disagrees with exec backend:\ngot  %v\nwant %v", got, want)
+		}
+	})
+
+	t.Run("commit-graph", func(t *testing.T) {
+		cmd := exec.Command("git", "commit-graph", "write", "--reachable")
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("git commit-graph not supported: %v\n%s", err, output)
+		}
+
+		got, err := goGitBackend{}.mergedBranches(gitRepo)
+		if err != nil {
+			t.Fatalf("go-git mergedBranches failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("go-git backend disagrees with exec backend:\ngot  %v\nwant %v", got, want)
+		}
+	})
+}
+
+func TestListBranches_GoGitBackend(t *testing.T) {
+	dir := setupSyntheticRepo(t, 10, 20)
+
+	gitRepo, _ := NewGitRepo(dir)
+	if err := gitRepo.SetBackend(BackendGoGit); err != nil {
+		t.Fatalf("SetBackend failed: %v", err)
+	}
+
+	branches, err := gitRepo.ListBranches(30, nil)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+
+	merged := 0
+	for _, b := range branches {
+		if b.IsMerged {
+			merged++
+		}
+	}
+	if merged != 10 {
+		t.Errorf("expected 10 merged branches, got %d", merged)
+	}
+}
+
+func TestGoGitBackend_DeleteRemoteBranch(t *testing.T) {
+	localDir, local, remote := setupRemoteRepo(t, "feature")
+
+	gitRepo, _ := NewGitRepo(localDir)
+	if err := gitRepo.SetBackend(BackendGoGit); err != nil {
+		t.Fatalf("SetBackend failed: %v", err)
+	}
+
+	if err := gitRepo.DeleteRemoteBranches([]RemoteDeletion{{Name: "feature"}})[0]; err != nil {
+		t.Fatalf("DeleteRemoteBranches failed: %v", err)
+	}
+
+	if _, err := remote.Reference(plumbing.NewBranchReferenceName("feature"), true); err == nil {
+		t.Error("branch still exists on remote")
+	}
+	if _, err := local.Reference(plumbing.NewRemoteReferenceName("origin", "feature"), true); err == nil {
+		t.Error("remote-tracking branch still exists")
+	}
+
+	// Nothing is pushed for a branch the remote doesn't have
+	if err := gitRepo.DeleteRemoteBranches([]RemoteDeletion{{Name: "feature"}})[0]; !errors.Is(err, ErrRemoteBranchNotFound) {
+		t.Errorf("expected ErrRemoteBranchNotFound, got %v", err)
+	}
+}
+
+func TestDeleteRemoteBranch_ConfiguredRemote(t *testing.T) {
+	localDir, local, remote := setupRemoteRepo(t, "feature")
+
+	remoteDir := remote.Storer.(*filesystem.Storage).Filesystem().Root()
+	if _, err := local.CreateRemote(&config.RemoteConfig{Name: "upstream", URLs: []string{remoteDir}}); err != nil {
+		t.Fatalf("failed to add remote: %v", err)
+	}
+	if err := local.Fetch(&git.FetchOptions{RemoteName: "upstream"}); err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+
+	gitRepo, _ := NewGitRepo(localDir)
+	if err := gitRepo.SetRemote("missing"); err == nil {
+		t.Error("expected an error for an unknown remote")
+	}
+	if err := gitRepo.SetRemote("upstream"); err != nil {
+		t.Fatalf("SetRemote failed: %v", err)
+	}
+	if err := gitRepo.SetBackend(BackendGoGit); err != nil {
+		t.Fatalf("SetBackend failed: %v", err)
+	}
+
+	if err := gitRepo.DeleteRemoteBranches([]RemoteDeletion{{Name: "feature"}})[0]; err != nil {
+		t.Fatalf("DeleteRemoteBranches failed: %v", err)
+	}
+	if _, err := remote.Reference(plumbing.NewBranchReferenceName("feature"), true); err == nil {
+		t.Error("branch still exists on remote")
+	}
+	if _, err := local.Reference(plumbing.NewRemoteReferenceName("upstream", "feature"), true); err == nil {
+		t.Error("remote-tracking branch still exists")
+	}
+}
+
+func TestDeleteBranch_ChangedSinceAnalysis(t *testing.T) {
+	for _, backend := range []Backend{BackendExec, BackendGoGit} {
+		t.Run(string(backend), func(t *testing.T) {
+			localDir, local, remote := setupRemoteRepo(t, "feature")
+
+			gitRepo, _ := NewGitRepo(localDir)
+			if err := gitRepo.SetBackend(backend); err != nil {
+				t.Fatalf("SetBackend failed: %v", err)
+			}
+			branches, err := gitRepo.ListBranches(30, nil)
+			if err != nil || len(branches) != 1 {
+				t.Fatalf("ListBranches = %+v, %v", branches, err)
+			}
+			analyzed := branches[0]
+
+			// Commit to the branch and push it after the analysis
+			head, _ := local.Head()
+			parent, _ := local.CommitObject(head.Hash())
+			moved := commitOnto(t, local, parent, "late work")
+			ref := plumbing.NewBranchReferenceName("feature")
+			if err := local.Storer.SetReference(plumbing.NewHashReference(ref, moved.Hash)); err != nil {
+				t.Fatal(err)
+			}
+			if err := local.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{config.RefSpec(ref + ":" + ref)}}); err != nil {
+				t.Fatalf("failed to push: %v", err)
+			}
+
+			if err := gitRepo.DeleteBranch("feature", analyzed.Tip); !errors.Is(err, ErrBranchChanged) {
+				t.Errorf("DeleteBranch = %v, want ErrBranchChanged", err)
+			}
+			if _, err := gitRepo.ArchiveBranch("feature", analyzed.Tip, ArchiveRef, time.Now()); !errors.Is(err, ErrBranchChanged) {
+				t.Errorf("ArchiveBranch = %v, want ErrBranchChanged", err)
+			}
+			if archives, _ := gitRepo.ListArchives(); len(archives) != 0 {
+				t.Errorf("expected no archives, got %+v", archives)
+			}
+			if err := gitRepo.DeleteRemoteBranches([]RemoteDeletion{{Name: "feature", ExpectedTip: analyzed.Tip}})[0]; !errors.Is(err, ErrBranchChanged) {
+				t.Errorf("DeleteRemoteBranches = %v, want ErrBranchChanged", err)
+			}
+			if _, err := local.Reference(ref, true); err != nil {
+				t.Error("local branch was deleted")
+			}
+			if _, err := remote.Reference(ref, true); err != nil {
+				t.Error("remote branch was deleted")
+			}
+
+			// The current tip is deleted as usual
+			if err := gitRepo.DeleteBranch("feature", moved.Hash.String()); err != nil {
+				t.Errorf("DeleteBranch failed: %v", err)
+			}
+			if err := gitRepo.DeleteRemoteBranches([]RemoteDeletion{{Name: "feature", ExpectedTip: moved.Hash.String()}})[0]; err != nil {
+				t.Errorf("DeleteRemoteBranches failed: %v", err)
+			}
+			if _, err := remote.Reference(ref, true); err == nil {
+				t.Error("branch still exists on remote")
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleManifestFile is the name of the manifest written to each bundle session directory
+const BundleManifestFile = "manifest.json"
+
+// BundleEntry describes the bundle written for one branch. Base is the ref
+// whose history the bundle leaves out, and Prerequisites the commits of that
+// history the bundle builds on; they must be present to import it.
+type BundleEntry struct {
+	Branch        string    `json:"branch"`
+	Tip           string    `json:"tip"`
+	File          string    `json:"file"`
+	Base          string    `json:"base,omitempty"`
+	Prerequisites []string  `json:"prerequisites,omitempty"`
+	Commits       int       `json:"commits"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BundleManifest lists the bundles written during one cleanup session
+type BundleManifest struct {
+	Repository    string        `json:"repository"`
+	DefaultBranch string        `json:"default_branch"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Bundles       []BundleEntry `json:"bundles"`
+}
+
+// BundleSession writes bundles of the branches deleted in one cleanup session
+// to their own directory, with a manifest that is updated after every bundle
+type BundleSession struct {
+	dir      string
+	manifest BundleManifest
+}
+
+// NewBundleSession prepares a session under root, in a directory named after
+// the repository and the time. The directory is created with the first bundle.
+func (g *GitRepo) NewBundleSession(root string, now time.Time) (*BundleSession, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("writing bundles requires git on PATH: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s", filepath.Base(g.repoPath), now.Format("20060102-150405"))
+	return &BundleSession{
+		dir: filepath.Join(root, name),
+		manifest: BundleManifest{
+			Repository:    g.repoPath,
+			DefaultBranch: g.defaultBranch,
+			CreatedAt:     now,
+			Bundles:       []BundleEntry{},
+		},
+	}, nil
+}
+
+// Dir returns the session directory
+func (s *BundleSession) Dir() string {
+	return s.dir
+}
+
+// Write bundles the commits of branch that are not in the default branch and
+// records the bundle in the manifest. A branch without such commits, usually
+// because it is merged, is bundled with just its tip commit so it can still be
+// restored. The bundle holds the analyzed tip, even if the branch has moved
+// since. Returns the path of the bundle.
+func (s *BundleSession) Write(g *GitRepo, branch Branch) (string, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	base := "refs/heads/" + g.defaultBranch
+	baseTip, err := g.runGit("rev-parse", "--verify", base)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", g.defaultBranch, err)
+	}
+	commits, prerequisites, err := g.bundleRange(branch.Tip, "^"+strings.TrimSpace(baseTip))
+	if err == nil && commits == 0 {
+		base = ""
+		commits, prerequisites, err = g.bundleRange(branch.Tip, "^"+branch.Tip+"^@")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to list commits of %s: %w", branch.Name, err)
+	}
+
+	// Escaping keeps names such as a/b and a_b apart
+	file := fmt.Sprintf("%s-%.8s.bundle", url.PathEscape(branch.Name), branch.Tip)
+	path := filepath.Join(s.dir, file)
+	if err := g.writeBundle(path, "refs/heads/"+branch.Name, branch.Tip, prerequisites); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to bundle %s: %w", branch.Name, err)
+	}
+	if _, err := g.runGit("bundle", "verify", path); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to verify bundle of %s: %w", branch.Name, err)
+	}
+
+	s.manifest.Bundles = append(s.manifest.Bundles, BundleEntry{
+		Branch:        branch.Name,
+		Tip:           branch.Tip,
+		File:          file,
+		Base:          base,
+		Prerequisites: prerequisites,
+		Commits:       commits,
+		CreatedAt:     time.Now(),
+	})
+	if err := s.saveManifest(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// bundleRange returns the number of commits reachable from tip but not from
+// exclude, and the excluded commits they build on
+func (g *GitRepo) bundleRange(tip, exclude string) (commits int, prerequisites []string, err error) {
+	output, err := g.runGit("rev-list", "--boundary", tip, exclude)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, line := range strings.Fields(output) {
+		if commit, ok := strings.CutPrefix(line, "-"); ok {
+			prerequisites = append(prerequisites, commit)
+		} else {
+			commits++
+		}
+	}
+	return commits, prerequisites, nil
+}
+
+// writeBundle writes a bundle with ref pointing at tip, holding the objects
+// reachable from tip that are not reachable from prerequisites. It is written
+// the way git bundle create does, which can only bundle the current value of
+// a ref.
+func (g *GitRepo) writeBundle(path, ref, tip string, prerequisites []string) error {
+	format, err := g.runGit("rev-parse", "--show-object-format")
+	if err != nil {
+		return err
+	}
+
+	var header, revs strings.Builder
+	if format = strings.TrimSpace(format); format == "sha1" {
+		header.WriteString("# v2 git bundle\n")
+	} else {
+		fmt.Fprintf(&header, "# v3 git bundle\n@object-format=%s\n", format)
+	}
+	fmt.Fprintf(&revs, "%s\n", tip)
+	for _, commit := range prerequisites {
+		fmt.Fprintf(&header, "-%s\n", commit)
+		fmt.Fprintf(&revs, "^%s\n", commit)
+	}
+	fmt.Fprintf(&header, "%s %s\n\n", tip, ref)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(header.String()); err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := g.gitCommand("pack-objects", "--stdout", "--thin", "--delta-base-offset", "--revs")
+	cmd.Stdin = strings.NewReader(revs.String())
+	cmd.Stdout = f
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w\nOutput: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return f.Close()
+}
+
+func (s *BundleSession) saveManifest() error {
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+
+	// Write atomically so an interrupted session leaves a valid manifest
+	path := filepath.Join(s.dir, BundleManifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write bundle manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write bundle manifest: %w", err)
+	}
+	return nil
+}
+
+// LoadBundleManifest reads the manifest of a bundle session directory
+func LoadBundleManifest(dir string) (*BundleManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, BundleManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle manifest: %w", err)
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// ImportBundle recreates the branches contained in a bundle file and returns
+// their names. Existing branches are never overwritten.
+func (g *GitRepo) ImportBundle(path string) ([]string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("importing bundles requires git on PATH: %w", err)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := g.runGit("bundle", "verify", path); err != nil {
+		return nil, fmt.Errorf("cannot import %s: %w", path, err)
+	}
+	heads, err := g.runGit("bundle", "list-heads", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var refspecs, names []string
+	for _, line := range strings.Split(heads, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "refs/heads/") {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "refs/heads/")
+		if _, err := g.runGit("rev-parse", "--verify", "--quiet", fields[1]); err == nil {
+			return nil, fmt.Errorf("cannot import %s: branch %s already exists", path, name)
+		}
+		refspecs = append(refspecs, fields[1]+":"+fields[1])
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("cannot import %s: the bundle contains no branches", path)
+	}
+
+	// Leave FETCH_HEAD alone: prune --offline reads it as the last fetch
+	args := append([]string{"fetch", "--quiet", "--no-write-fetch-head", path}, refspecs...)
+	if _, err := g.runGit(args...); err != nil {
+		return nil, fmt.Errorf("failed to import %s: %w", path, err)
+	}
+	return names, nil
+}
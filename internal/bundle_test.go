@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestBundleSession(t *testing.T) {
+	requireGit(t)
+	tmpDir, repo := setupTestRepo(t)
+	head, _ := repo.Head()
+	tip, _ := repo.CommitObject(head.Hash())
+	unmerged := commitOnto(t, repo, commitOnto(t, repo, tip, "one"), "two")
+
+	tips := map[string]plumbing.Hash{"merged": tip.Hash, "feature/unmerged": unmerged.Hash}
+	for name, hash := range tips {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The branch moves after the analysis; its bundle keeps the analyzed tip
+	moved := commitOnto(t, repo, unmerged, "three")
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature/unmerged"), moved.Hash)); err != nil {
+		t.Fatal(err)
+	}
+
+	gitRepo, err := NewGitRepo(tmpDir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	root := t.TempDir()
+	session, err := gitRepo.NewBundleSession(root, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("NewBundleSession failed: %v", err)
+	}
+	if want := filepath.Join(root, filepath.Base(tmpDir)+"-20240301-120000"); session.Dir() != want {
+		t.Errorf("Dir() = %s, want %s", session.Dir(), want)
+	}
+
+	var paths []string
+	for _, name := range []string{"merged", "feature/unmerged"} {
+		path, err := session.Write(gitRepo, Branch{Name: name, Tip: tips[name].String()})
+		if err != nil {
+			t.Fatalf("Write(%s) failed: %v", name, err)
+		}
+		paths = append(paths, path)
+		if err := gitRepo.DeleteBranch(name, ""); err != nil {
+			t.Fatalf("DeleteBranch failed: %v", err)
+		}
+	}
+
+	manifest, err := LoadBundleManifest(session.Dir())
+	if err != nil {
+		t.Fatalf("LoadBundleManifest failed: %v", err)
+	}
+	if manifest.DefaultBranch != "master" || len(manifest.Bundles) != 2 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	merged, feature := manifest.Bundles[0], manifest.Bundles[1]
+	if merged.Base != "" || merged.Commits != 1 || len(merged.Prerequisites) != 0 {
+		t.Errorf("merged branch should be bundled with its tip only: %+v", merged)
+	}
+	if feature.Base != "refs/heads/master" || feature.Commits != 2 || feature.File != "feature%2Funmerged-"+unmerged.Hash.String()[:8]+".bundle" {
+		t.Errorf("unexpected entry: %+v", feature)
+	}
+	if len(feature.Prerequisites) != 1 || feature.Prerequisites[0] != tip.Hash.String() {
+		t.Errorf("expected %s as the only prerequisite, got %v", tip.Hash, feature.Prerequisites)
+	}
+
+	for _, path := range paths {
+		if _, err := gitRepo.ImportBundle(path); err != nil {
+			t.Fatalf("ImportBundle failed: %v", err)
+		}
+	}
+	for name, hash := range tips {
+		ref, err := repo.Reference(plumbing.NewBranchReferenceName(name), false)
+		if err != nil || ref.Hash() != hash {
+			t.Errorf("branch %s not restored to %s: %v", name, hash, err)
+		}
+	}
+	// FETCH_HEAD still describes the last fetch from the remote
+	if _, err := os.Stat(filepath.Join(tmpDir, ".git", "FETCH_HEAD")); !os.IsNotExist(err) {
+		t.Errorf("importing wrote FETCH_HEAD: %v", err)
+	}
+
+	// Existing branches are not overwritten
+	if _, err := gitRepo.ImportBundle(paths[0]); err == nil {
+		t.Error("expected an error importing over an existing branch")
+	}
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// cacheVersion is bumped whenever the cache layout or the meaning of its
+// entries changes; caches written with another version are discarded
+const cacheVersion = 2
+
+// mergeModeAncestor identifies merge detection by tip ancestry. It is part of
+// every cache key so results of other detection modes never mix.
+const mergeModeAncestor = "ancestor"
+
+// analysisCache stores merge detection results keyed by the branch tip, the
+// default branch tip and the detection mode. Results only change when one of
+// those tips moves, so repeated runs can skip merge detection entirely.
+type analysisCache struct {
+	path    string
+	Version int             `json:"version"`
+	Entries map[string]bool `json:"entries"`
+	used    map[string]bool
+}
+
+// loadAnalysisCache reads the cache at path. A missing, unreadable or
+// outdated cache yields an empty cache rather than an error.
+func loadAnalysisCache(path string) *analysisCache {
+	cache := &analysisCache{
+		path:    path,
+		Version: cacheVersion,
+		Entries: make(map[string]bool),
+		used:    make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+
+	var stored analysisCache
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != cacheVersion || stored.Entries == nil {
+		return cache
+	}
+	cache.Entries = stored.Entries
+	return cache
+}
+
+func cacheKey(branchTip, targetTip plumbing.Hash, mode string) string {
+	return branchTip.String() + ":" + targetTip.String() + ":" + mode
+}
+
+// lookup returns the cached merge status for key and whether it was present
+func (c *analysisCache) lookup(key string) (merged, ok bool) {
+	merged, ok = c.Entries[key]
+	if ok {
+		c.used[key] = merged
+	}
+	return merged, ok
+}
+
+func (c *analysisCache) store(key string, merged bool) {
+	c.Entries[key] = merged
+	c.used[key] = merged
+}
+
+// save writes the entries looked up or stored during this run, dropping
+// entries for tips that no longer exist so the cache does not grow forever.
+// The file is replaced atomically so concurrent readers never see a partial write.
+func (c *analysisCache) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(analysisCache{Version: cacheVersion, Entries: c.used})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".cache-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
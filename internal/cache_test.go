@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestAnalysisCache_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "branch-clean", "cache")
+	branch := plumbing.NewHash("1111111111111111111111111111111111111111")
+	target := plumbing.NewHash("2222222222222222222222222222222222222222")
+	key := cacheKey(branch, target, mergeModeAncestor)
+
+	cache := loadAnalysisCache(path)
+	if _, ok := cache.lookup(key); ok {
+		t.Fatal("expected a missing cache to be empty")
+	}
+	cache.store(key, true)
+	if err := cache.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	merged, ok := loadAnalysisCache(path).lookup(key)
+	if !ok || !merged {
+		t.Errorf("lookup = %v, %v, want true, true", merged, ok)
+	}
+	if _, ok := loadAnalysisCache(path).lookup(cacheKey(branch, target, "other")); ok {
+		t.Error("expected entries of another detection mode to miss")
+	}
+}
+
+func TestAnalysisCache_Invalidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	key := cacheKey(plumbing.ZeroHash, plumbing.ZeroHash, mergeModeAncestor)
+
+	for name, content := range map[string]string{
+		"old version": `{"version":1,"entries":{"` + key + `":true}}`,
+		"corrupt":     `{"version":`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := loadAnalysisCache(path).lookup(key); ok {
+			t.Errorf("%s: expected cache to be discarded", name)
+		}
+	}
+}
+
+func TestAnalysisCache_SaveDropsUnusedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	used := cacheKey(plumbing.NewHash("1111111111111111111111111111111111111111"), plumbing.ZeroHash, mergeModeAncestor)
+	unused := cacheKey(plumbing.NewHash("3333333333333333333333333333333333333333"), plumbing.ZeroHash, mergeModeAncestor)
+
+	cache := loadAnalysisCache(path)
+	cache.store(used, true)
+	cache.store(unused, false)
+	if err := cache.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	cache = loadAnalysisCache(path)
+	cache.lookup(used)
+	if err := cache.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	cache = loadAnalysisCache(path)
+	if _, ok := cache.lookup(used); !ok {
+		t.Error("expected the used entry to be kept")
+	}
+	if _, ok := cache.lookup(unused); ok {
+		t.Error("expected the unused entry to be dropped")
+	}
+}
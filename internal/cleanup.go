@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrRemoteDeleteFailed is returned when local deletions succeeded but one or
+// more remote deletions failed
+var ErrRemoteDeleteFailed = errors.New("some remote branches failed to delete")
+
+// Outcomes recorded for each deletion step
+const (
+	OutcomeDeleted     = "deleted"
+	OutcomeFailed      = "failed"
+	OutcomeSkipped     = "skipped"
+	OutcomeWouldDelete = "would-delete"
+	OutcomeRestored    = "restored"
+	// OutcomeWouldArchive is the dry-run outcome of a branch that would be
+	// archived instead of deleted
+	OutcomeWouldArchive = "would-archive"
+	// OutcomeChanged means the branch was kept because its tip moved after
+	// the analysis that selected it
+	OutcomeChanged = "changed"
+	// OutcomeRefused means the remote branch was kept because it has commits
+	// that were neither analyzed nor merged
+	OutcomeRefused = "refused"
+)
+
+// DeletionResult is the outcome of deleting a branch locally or on the remote
+type DeletionResult struct {
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CleanupResult records what happened to a single branch during cleanup.
+// Remote is nil when remote deletion was not requested. Archive is the ref the
+// branch was moved to with --archive.
+type CleanupResult struct {
+	Branch  string          `json:"branch"`
+	Tip     string          `json:"tip"`
+	DryRun  bool            `json:"dry_run"`
+	Archive string          `json:"archive,omitempty"`
+	Local   DeletionResult  `json:"local"`
+	Remote  *DeletionResult `json:"remote,omitempty"`
+}
+
+// CleanupSummary totals the outcomes of a cleanup session
+type CleanupSummary struct {
+	DryRun        bool `json:"dry_run"`
+	Selected      int  `json:"selected"`
+	LocalDeleted  int  `json:"local_deleted"`
+	LocalFailed   int  `json:"local_failed"`
+	LocalChanged  int  `json:"local_changed"`
+	RemoteDeleted int  `json:"remote_deleted"`
+	RemoteFailed  int  `json:"remote_failed"`
+	RemoteChanged int  `json:"remote_changed"`
+	RemoteRefused int  `json:"remote_refused"`
+}
+
+// CleanupReport is the document written by the json result format
+type CleanupReport struct {
+	Results []CleanupResult `json:"results"`
+	Summary CleanupSummary  `json:"summary"`
+}
+
+// CleanupReporter collects per-branch results and writes them as JSON or NDJSON.
+// NDJSON results are written as soon as they are added, followed by a summary line.
+type CleanupReporter struct {
+	ndjson  bool
+	report  CleanupReport
+	encoder *json.Encoder
+}
+
+// NewCleanupReporter returns a reporter writing format ("json" or "ndjson") to w
+func NewCleanupReporter(w io.Writer, format string, dryRun bool) (*CleanupReporter, error) {
+	if format != "json" && format != "ndjson" {
+		return nil, fmt.Errorf("invalid result format: %s (must be 'json' or 'ndjson')", format)
+	}
+
+	r := &CleanupReporter{
+		ndjson:  format == "ndjson",
+		encoder: json.NewEncoder(w),
+		report: CleanupReport{
+			Results: []CleanupResult{},
+			Summary: CleanupSummary{DryRun: dryRun},
+		},
+	}
+	if !r.ndjson {
+		r.encoder.SetIndent("", "  ")
+	}
+	return r, nil
+}
+
+// Add records the result for one branch
+func (r *CleanupReporter) Add(result CleanupResult) error {
+	result.DryRun = r.report.Summary.DryRun
+	r.report.Results = append(r.report.Results, result)
+
+	summary := &r.report.Summary
+	summary.Selected++
+	switch result.Local.Outcome {
+	case OutcomeDeleted:
+		summary.LocalDeleted++
+	case OutcomeFailed:
+		summary.LocalFailed++
+	case OutcomeChanged:
+		summary.LocalChanged++
+	}
+	if result.Remote != nil {
+		switch result.Remote.Outcome {
+		case OutcomeDeleted:
+			summary.RemoteDeleted++
+		case OutcomeFailed:
+			summary.RemoteFailed++
+		case OutcomeChanged:
+			summary.RemoteChanged++
+		case OutcomeRefused:
+			summary.RemoteRefused++
+		}
+	}
+
+	if r.ndjson {
+		return r.encode(struct {
+			Type string `json:"type"`
+			CleanupResult
+		}{"result", result})
+	}
+	return nil
+}
+
+// Close writes the summary line (ndjson) or the complete report (json)
+func (r *CleanupReporter) Close() error {
+	if r.ndjson {
+		return r.encode(struct {
+			Type string `json:"type"`
+			CleanupSummary
+		}{"summary", r.report.Summary})
+	}
+	return r.encode(r.report)
+}
+
+func (r *CleanupReporter) encode(v interface{}) error {
+	if err := r.encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+	return nil
+}
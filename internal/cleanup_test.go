@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func sampleResults() []CleanupResult {
+	return []CleanupResult{
+		{
+			Branch: "feature/a",
+			Tip:    "1111111111111111111111111111111111111111",
+			Local:  DeletionResult{Outcome: OutcomeDeleted},
+			Remote: &DeletionResult{Outcome: OutcomeDeleted},
+		},
+		{
+			Branch: "feature/b",
+			Tip:    "2222222222222222222222222222222222222222",
+			Local:  DeletionResult{Outcome: OutcomeDeleted},
+			Remote: &DeletionResult{Outcome: OutcomeFailed, Error: "permission denied"},
+		},
+		{
+			Branch: "feature/c",
+			Local:  DeletionResult{Outcome: OutcomeFailed, Error: "cannot delete current branch"},
+			Remote: &DeletionResult{Outcome: OutcomeSkipped},
+		},
+	}
+}
+
+func TestCleanupReporter_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	reporter, err := NewCleanupReporter(&buf, "json", false)
+	if err != nil {
+		t.Fatalf("NewCleanupReporter failed: %v", err)
+	}
+
+	for _, r := range sampleResults() {
+		if err := reporter.Add(r); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Error("json reporter should not write before Close")
+	}
+	if err := reporter.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var report CleanupReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	want := CleanupSummary{Selected: 3, LocalDeleted: 2, LocalFailed: 1, RemoteDeleted: 1, RemoteFailed: 1}
+	if report.Summary != want {
+		t.Errorf("summary = %+v, want %+v", report.Summary, want)
+	}
+	if len(report.Results) != 3 || report.Results[1].Remote.Error != "permission denied" {
+		t.Errorf("unexpected results: %+v", report.Results)
+	}
+}
+
+func TestCleanupReporter_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	reporter, err := NewCleanupReporter(&buf, "ndjson", true)
+	if err != nil {
+		t.Fatalf("NewCleanupReporter failed: %v", err)
+	}
+
+	for _, r := range sampleResults() {
+		if err := reporter.Add(r); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := reporter.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var types []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line struct {
+			Type   string `json:"type"`
+			DryRun bool   `json:"dry_run"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		if !line.DryRun {
+			t.Errorf("expected dry_run on every line: %s", scanner.Text())
+		}
+		types = append(types, line.Type)
+	}
+
+	if len(types) != 4 || types[0] != "result" || types[3] != "summary" {
+		t.Errorf("unexpected line types: %v", types)
+	}
+}
+
+func TestCleanupReporter_EmptyReport(t *testing.T) {
+	var buf bytes.Buffer
+	reporter, _ := NewCleanupReporter(&buf, "json", false)
+	if err := reporter.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var report map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if string(report["results"]) != "[]" {
+		t.Errorf("expected empty results array, got %s", report["results"])
+	}
+}
+
+func TestNewCleanupReporter_InvalidFormat(t *testing.T) {
+	if _, err := NewCleanupReporter(&bytes.Buffer{}, "xml", false); err == nil {
+		t.Error("expected error for invalid format")
+	}
+}
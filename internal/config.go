@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,6 +13,51 @@ import (
 type Config struct {
 	StaleDays int      `yaml:"stale_days"`
 	Protected []string `yaml:"protected"`
+
+	// DefaultBranch overrides the detected default branch and Remote the
+	// remote used instead of origin
+	DefaultBranch string `yaml:"default_branch,omitempty"`
+	Remote        string `yaml:"remote,omitempty"`
+
+	// AuditLog overrides the path of the audit log. It is only read from the
+	// user configuration and workspace manifests, never from a repository.
+	AuditLog string `yaml:"audit_log,omitempty"`
+
+	// Sources lists the configuration files the values were read from
+	Sources []string `yaml:"-"`
+}
+
+// Merge returns a copy of c with the values set in override applied
+func (c *Config) Merge(override Config) *Config {
+	merged := *c
+	if override.StaleDays != 0 {
+		merged.StaleDays = override.StaleDays
+	}
+	if len(override.Protected) != 0 {
+		merged.Protected = override.Protected
+	}
+	if override.DefaultBranch != "" {
+		merged.DefaultBranch = override.DefaultBranch
+	}
+	if override.Remote != "" {
+		merged.Remote = override.Remote
+	}
+	if override.AuditLog != "" {
+		merged.AuditLog = override.AuditLog
+	}
+	merged.Sources = append(append([]string(nil), c.Sources...), override.Sources...)
+	return &merged
+}
+
+// Validate returns an error if a value of the merged configuration is out of range
+func (c *Config) Validate() error {
+	if c.StaleDays <= 0 {
+		if len(c.Sources) > 0 {
+			return fmt.Errorf("stale_days must be positive, got %d (from %s)", c.StaleDays, strings.Join(c.Sources, ", "))
+		}
+		return fmt.Errorf("stale_days must be positive, got %d", c.StaleDays)
+	}
+	return nil
 }
 
 // DefaultConfig returns the default configuration
@@ -53,10 +99,52 @@ func LoadConfig() (*Config, error) {
 	if len(config.Protected) == 0 {
 		config.Protected = []string{"main", "master", "develop", "release/*"}
 	}
+	config.AuditLog = resolvePath(config.AuditLog, home)
+	config.Sources = []string{configPath}
 
 	return &config, nil
 }
 
+// resolvePath resolves a relative path against dir, the directory of the file
+// that set it
+func resolvePath(path, dir string) string {
+	if path == "" {
+		return ""
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path
+}
+
+// RepoConfigFile is the name of the optional per-repository configuration
+// file, read from the repository root
+const RepoConfigFile = ".branch-clean.yaml"
+
+// LoadRepoConfig loads the configuration file of the repository at repoPath.
+// Values it sets override those of base, except audit_log, which is ignored;
+// if the file doesn't exist, base is returned unchanged.
+func LoadRepoConfig(repoPath string, base *Config) (*Config, error) {
+	data, err := os.ReadFile(filepath.Join(repoPath, RepoConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return base, nil
+		}
+		return nil, fmt.Errorf("failed to read repository config file: %w", err)
+	}
+
+	var repoConfig Config
+	if err := yaml.Unmarshal(data, &repoConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse repository config file: %w", err)
+	}
+	// A committed file must not redirect or disable the audit trail
+	repoConfig.AuditLog = ""
+	repoConfig.Sources = []string{filepath.Join(repoPath, RepoConfigFile)}
+
+	return base.Merge(repoConfig), nil
+}
+
 // SaveConfig saves the configuration to a file
 func SaveConfig(config *Config) error {
 	home, err := os.UserHomeDir()
@@ -3,6 +3,8 @@ package internal
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -106,3 +108,89 @@ func TestLoadConfig_MissingValues(t *testing.T) {
 		t.Error("expected default protected patterns")
 	}
 }
+
+func TestLoadConfig_AuditLog(t *testing.T) {
+	tmpHome := t.TempDir()
+	setTestHome(t, tmpHome)
+
+	configPath := filepath.Join(tmpHome, ".branch-clean.yaml")
+	if err := os.WriteFile(configPath, []byte("audit_log: logs/audit.log\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if want := filepath.Join(tmpHome, "logs", "audit.log"); config.AuditLog != want {
+		t.Errorf("expected audit_log to be resolved against the home directory, got %s, want %s", config.AuditLog, want)
+	}
+}
+
+func TestLoadRepoConfig(t *testing.T) {
+	repoDir := t.TempDir()
+	base := DefaultConfig()
+
+	config, err := LoadRepoConfig(repoDir, base)
+	if err != nil {
+		t.Fatalf("LoadRepoConfig failed: %v", err)
+	}
+	if config != base {
+		t.Error("expected the base config without a repository config file")
+	}
+
+	if err := os.WriteFile(filepath.Join(repoDir, RepoConfigFile), []byte("protected:\n  - trunk\naudit_log: /dev/null\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	config, err = LoadRepoConfig(repoDir, base)
+	if err != nil {
+		t.Fatalf("LoadRepoConfig failed: %v", err)
+	}
+	if config.StaleDays != base.StaleDays {
+		t.Errorf("expected StaleDays to be inherited, got %d", config.StaleDays)
+	}
+	if len(config.Protected) != 1 || config.Protected[0] != "trunk" {
+		t.Errorf("expected Protected to be overridden, got %v", config.Protected)
+	}
+	if len(base.Protected) == 1 {
+		t.Error("expected the base config to be left unchanged")
+	}
+	if config.AuditLog != "" {
+		t.Errorf("expected the repository's audit_log to be ignored, got %s", config.AuditLog)
+	}
+	if want := filepath.Join(repoDir, RepoConfigFile); len(config.Sources) != 1 || config.Sources[0] != want {
+		t.Errorf("expected Sources to be [%s], got %v", want, config.Sources)
+	}
+}
+
+func TestConfigMerge(t *testing.T) {
+	base := &Config{StaleDays: 30, Protected: []string{"main"}, Remote: "origin"}
+
+	merged := base.Merge(Config{StaleDays: 60, DefaultBranch: "trunk"})
+	want := Config{StaleDays: 60, Protected: []string{"main"}, DefaultBranch: "trunk", Remote: "origin"}
+	if !reflect.DeepEqual(*merged, want) {
+		t.Errorf("Merge() = %+v, want %+v", *merged, want)
+	}
+	if base.StaleDays != 30 || base.DefaultBranch != "" {
+		t.Errorf("expected the base config to be left unchanged, got %+v", *base)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("expected the default config to be valid, got %v", err)
+	}
+
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, RepoConfigFile), []byte("stale_days: -5\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	config, err := LoadRepoConfig(repoDir, DefaultConfig())
+	if err != nil {
+		t.Fatalf("LoadRepoConfig failed: %v", err)
+	}
+	err = config.Validate()
+	if err == nil || !strings.Contains(err.Error(), RepoConfigFile) {
+		t.Errorf("expected an error naming the repository config file, got %v", err)
+	}
+}
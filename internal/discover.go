@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/filesystem/dotgit"
+)
+
+// ErrNotRepository is returned when no repository is found for a path
+var ErrNotRepository = fmt.Errorf("not a git repository (or any of the parent directories)")
+
+// FindRepository returns the root of the repository containing path, the way
+// git discovers it: the nearest directory, starting at path and walking up,
+// that has a .git directory or file (linked worktrees and submodules) or is
+// itself a bare repository. GIT_DIR takes precedence when set.
+func FindRepository(path string) (string, error) {
+	if gitDir := os.Getenv("GIT_DIR"); gitDir != "" {
+		return filepath.Abs(gitDir)
+	}
+
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, git.GitDirName)); err == nil {
+			return dir, nil
+		}
+		if isBareRepository(dir) {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%w: %s", ErrNotRepository, path)
+		}
+		dir = parent
+	}
+}
+
+// isBareRepository reports whether dir is a git directory, using the same
+// checks as git: a HEAD file and objects and refs directories
+func isBareRepository(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || info.IsDir() {
+		return false
+	}
+	for _, sub := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// isBare reports whether repo has no working tree
+func isBare(repo *git.Repository) bool {
+	cfg, err := repo.Config()
+	return err == nil && cfg.Core.IsBare
+}
+
+// headBranch returns the branch HEAD points to, if that branch exists
+func headBranch(repo *git.Repository) (string, bool) {
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil || head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+		return "", false
+	}
+	if _, err := repo.Reference(head.Target(), true); err != nil {
+		return "", false
+	}
+	return head.Target().Short(), true
+}
+
+// IsBare reports whether the repository is bare. Its branches are deleted
+// directly, so there is no remote to delete them from.
+func (g *GitRepo) IsBare() bool {
+	return g.bare
+}
+
+// openRepository opens the repository whose root was returned by
+// FindRepository. Linked worktrees share the refs and objects of their main
+// repository. With GIT_DIR set, GIT_WORK_TREE selects the working tree.
+func openRepository(root string) (*git.Repository, error) {
+	if gitDir := os.Getenv("GIT_DIR"); gitDir != "" {
+		dot := osfs.New(root)
+		common, err := commonDirFilesystem(root)
+		if err != nil {
+			return nil, err
+		}
+
+		var worktree billy.Filesystem
+		if workTree := os.Getenv("GIT_WORK_TREE"); workTree != "" {
+			worktree = osfs.New(workTree)
+		}
+		storage := filesystem.NewStorage(dotgit.NewRepositoryFilesystem(dot, common), cache.NewObjectLRUDefault())
+		return git.Open(storage, worktree)
+	}
+
+	return git.PlainOpenWithOptions(root, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
+
+// commonDir returns the directory holding the refs and objects shared by all
+// worktrees of the repository whose git directory is gitDir
+func commonDir(gitDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if os.IsNotExist(err) {
+		return gitDir, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read commondir: %w", err)
+	}
+
+	dir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+func commonDirFilesystem(gitDir string) (billy.Filesystem, error) {
+	dir, err := commonDir(gitDir)
+	if err != nil || dir == gitDir {
+		return nil, err
+	}
+	return osfs.New(dir), nil
+}
+
+// worktreeCheckingOut returns the path of another worktree of the repository
+// that has branch checked out, or "" if there is none
+func (g *GitRepo) worktreeCheckingOut(branch string) (string, error) {
+	gitDir, err := g.gitDir()
+	if err != nil {
+		return "", nil
+	}
+	common, err := commonDir(gitDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Git directories of the other worktrees, mapped to their working trees
+	others := make(map[string]string)
+	if common != gitDir {
+		others[common] = filepath.Dir(common)
+	}
+	entries, err := os.ReadDir(filepath.Join(common, "worktrees"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	for _, entry := range entries {
+		dir := filepath.Join(common, "worktrees", entry.Name())
+		if dir == gitDir {
+			continue
+		}
+		// gitdir holds the path of the worktree's .git file
+		others[dir] = entry.Name()
+		if data, err := os.ReadFile(filepath.Join(dir, "gitdir")); err == nil {
+			others[dir] = filepath.Dir(strings.TrimSpace(string(data)))
+		}
+	}
+
+	want := "ref: " + plumbing.NewBranchReferenceName(branch).String()
+	for dir, worktree := range others {
+		head, err := os.ReadFile(filepath.Join(dir, "HEAD"))
+		if err == nil && strings.TrimSpace(string(head)) == want {
+			return worktree, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func runGitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func TestFindRepository_Subdirectory(t *testing.T) {
+	tmpDir, _ := setupTestRepo(t)
+	subDir := filepath.Join(tmpDir, "a", "b")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := FindRepository(subDir)
+	if err != nil {
+		t.Fatalf("FindRepository failed: %v", err)
+	}
+	if root != tmpDir {
+		t.Errorf("root = %s, want %s", root, tmpDir)
+	}
+
+	gitRepo, err := NewGitRepo(subDir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	if gitRepo.Path() != tmpDir {
+		t.Errorf("Path() = %s, want %s", gitRepo.Path(), tmpDir)
+	}
+}
+
+func TestFindRepository_NotRepository(t *testing.T) {
+	_, err := FindRepository(t.TempDir())
+	if !errors.Is(err, ErrNotRepository) {
+		t.Errorf("expected ErrNotRepository, got %v", err)
+	}
+}
+
+func TestFindRepository_GitDir(t *testing.T) {
+	tmpDir, _ := setupTestRepo(t)
+	t.Setenv("GIT_DIR", filepath.Join(tmpDir, ".git"))
+	t.Setenv("GIT_WORK_TREE", tmpDir)
+
+	gitRepo, err := NewGitRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	if gitRepo.defaultBranch != "master" {
+		t.Errorf("defaultBranch = %s, want master", gitRepo.defaultBranch)
+	}
+}
+
+func TestNewGitRepo_Bare(t *testing.T) {
+	requireGit(t)
+	tmpDir, _ := setupTestRepo(t)
+	runGitIn(t, tmpDir, "branch", "feature")
+	runGitIn(t, tmpDir, "branch", "trunk")
+	bareDir := filepath.Join(t.TempDir(), "repo.git")
+	runGitIn(t, tmpDir, "clone", "--quiet", "--bare", tmpDir, bareDir)
+	runGitIn(t, bareDir, "symbolic-ref", "HEAD", "refs/heads/trunk")
+
+	gitRepo, err := NewGitRepo(filepath.Join(bareDir, "refs"))
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	if gitRepo.Path() != bareDir {
+		t.Errorf("Path() = %s, want %s", gitRepo.Path(), bareDir)
+	}
+	if !gitRepo.IsBare() {
+		t.Error("expected a bare repository")
+	}
+	// HEAD names the default branch, even though master exists
+	if gitRepo.defaultBranch != "trunk" {
+		t.Errorf("defaultBranch = %s, want trunk", gitRepo.defaultBranch)
+	}
+
+	branches, err := gitRepo.ListBranches(30, []string{"master"})
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	candidates := FilterBranches(branches, false, false)
+	if len(candidates) != 1 || candidates[0].Name != "feature" || !candidates[0].IsMerged {
+		t.Fatalf("unexpected candidates: %+v", candidates)
+	}
+
+	if err := gitRepo.DeleteBranch("feature", ""); err != nil {
+		t.Fatalf("DeleteBranch failed: %v", err)
+	}
+	runGitIn(t, bareDir, "fsck", "--no-progress")
+	if _, err := gitRepo.repo.Reference(plumbing.NewBranchReferenceName("feature"), false); err == nil {
+		t.Error("feature still exists")
+	}
+}
+
+func TestNewGitRepo_Worktree(t *testing.T) {
+	requireGit(t)
+	tmpDir, _ := setupTestRepo(t)
+	runGitIn(t, tmpDir, "branch", "done")
+	worktreeDir := filepath.Join(t.TempDir(), "wt")
+	runGitIn(t, tmpDir, "worktree", "add", "--quiet", "-b", "feature", worktreeDir)
+
+	gitRepo, err := NewGitRepo(worktreeDir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	if gitRepo.defaultBranch != "master" {
+		t.Errorf("defaultBranch = %s, want master", gitRepo.defaultBranch)
+	}
+
+	branches, err := gitRepo.ListBranches(30, nil)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	if len(branches) != 2 {
+		t.Errorf("expected 2 branches, got %d", len(branches))
+	}
+
+	// The cache is shared with the main worktree
+	if _, err := os.Stat(filepath.Join(tmpDir, ".git", "branch-clean", "cache")); err != nil {
+		t.Errorf("expected cache in the common git directory: %v", err)
+	}
+
+	// master is checked out in the main worktree
+	if err := gitRepo.DeleteBranch("master", ""); !errors.Is(err, ErrDefaultBranch) {
+		t.Errorf("expected ErrDefaultBranch, got %v", err)
+	}
+	if err := gitRepo.DeleteBranch("done", ""); err != nil {
+		t.Errorf("DeleteBranch failed: %v", err)
+	}
+
+	// From the main worktree, feature is checked out elsewhere
+	mainRepo, err := NewGitRepo(tmpDir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	if err := mainRepo.DeleteBranch("feature", ""); !errors.Is(err, ErrCurrentBranch) {
+		t.Errorf("expected ErrCurrentBranch, got %v", err)
+	}
+}
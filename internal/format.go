@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formatter writes a list of branches in a specific output format
+type Formatter interface {
+	Format(w io.Writer, branches []Branch) error
+}
+
+// OutputFormats lists the format names accepted by NewFormatter
+var OutputFormats = []string{"table", "json", "csv", "tsv", "yaml", "markdown"}
+
+// NewFormatter returns the formatter registered under name.
+// Returns an error listing the valid formats if name is unknown.
+func NewFormatter(name string) (Formatter, error) {
+	switch name {
+	case "table":
+		return tableFormatter{}, nil
+	case "json":
+		return jsonFormatter{}, nil
+	case "csv":
+		return delimitedFormatter{comma: ','}, nil
+	case "tsv":
+		return delimitedFormatter{comma: '\t'}, nil
+	case "yaml":
+		return yamlFormatter{}, nil
+	case "markdown":
+		return markdownFormatter{}, nil
+	}
+	return nil, fmt.Errorf("invalid output format: %s (must be one of: %s)", name, strings.Join(OutputFormats, ", "))
+}
+
+type tableFormatter struct{}
+
+func (tableFormatter) Format(w io.Writer, branches []Branch) error {
+	writeBranchTable(w, branches, terminalWidth)
+	return nil
+}
+
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, branches []Branch) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(branches); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
+
+// delimitedFormatter writes CSV or TSV depending on the separator
+type delimitedFormatter struct {
+	comma rune
+}
+
+func (f delimitedFormatter) Format(w io.Writer, branches []Branch) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = f.comma
+
+	if err := writer.Write([]string{"name", "status", "is_merged", "is_stale", "protected", "last_commit", "tip", "author", "error"}); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	for _, b := range branches {
+		record := []string{
+			b.Name,
+			b.Status(),
+			strconv.FormatBool(b.IsMerged),
+			strconv.FormatBool(b.IsStale),
+			strconv.FormatBool(b.Protected),
+			"",
+			b.Tip,
+			b.Author,
+			b.Error,
+		}
+		if !b.LastCommit.IsZero() {
+			record[5] = b.LastCommit.Format(time.RFC3339)
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+type yamlFormatter struct{}
+
+func (yamlFormatter) Format(w io.Writer, branches []Branch) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if branches == nil {
+		branches = []Branch{}
+	}
+	if err := encoder.Encode(branches); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return encoder.Close()
+}
+
+// markdownFormatter writes a GitHub Flavored Markdown table
+type markdownFormatter struct{}
+
+func (markdownFormatter) Format(w io.Writer, branches []Branch) error {
+	var sb strings.Builder
+	sb.WriteString("| Branch | Status | Age | Last Commit | Protected |\n")
+	sb.WriteString("|--------|--------|-----|-------------|-----------|\n")
+
+	for _, b := range branches {
+		protected := ""
+		if b.Protected {
+			protected = "yes"
+		}
+		fmt.Fprintf(&sb, "| `%s` | %s | %s | %s | %s |\n",
+			escapeMarkdownCell(b.Name),
+			b.Status(),
+			getAgeString(b.LastCommit),
+			getDateString(b.LastCommit),
+			protected)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// escapeMarkdownCell escapes characters that would break a table cell
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func testBranches() []Branch {
+	when := time.Date(2026, 1, 24, 10, 30, 0, 0, time.UTC)
+	return []Branch{
+		{Name: "feature/merged", IsMerged: true, LastCommit: when},
+		{Name: "bugfix/stale", IsStale: true, LastCommit: when},
+		{Name: "feature/pipe|name", LastCommit: when},
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	for _, name := range OutputFormats {
+		if _, err := NewFormatter(name); err != nil {
+			t.Errorf("NewFormatter(%q) failed: %v", name, err)
+		}
+	}
+
+	if _, err := NewFormatter("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (jsonFormatter{}).Format(&buf, testBranches()); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	var decoded []Branch
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(decoded) != 3 || decoded[0].Name != "feature/merged" {
+		t.Errorf("unexpected decoded branches: %+v", decoded)
+	}
+}
+
+func TestDelimitedFormatter(t *testing.T) {
+	tests := []struct {
+		name  string
+		comma rune
+	}{
+		{"csv", ','},
+		{"tsv", '\t'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (delimitedFormatter{comma: tt.comma}).Format(&buf, testBranches()); err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+
+			reader := csv.NewReader(&buf)
+			reader.Comma = tt.comma
+			records, err := reader.ReadAll()
+			if err != nil {
+				t.Fatalf("failed to parse output: %v", err)
+			}
+			if len(records) != 4 {
+				t.Fatalf("expected header + 3 rows, got %d", len(records))
+			}
+			if records[0][0] != "name" || records[1][1] != "merged" || records[2][1] != "stale" {
+				t.Errorf("unexpected records: %v", records)
+			}
+		})
+	}
+}
+
+func TestYAMLFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (yamlFormatter{}).Format(&buf, testBranches()); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	var decoded []Branch
+	if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+	if len(decoded) != 3 || !decoded[0].IsMerged {
+		t.Errorf("unexpected decoded branches: %+v", decoded)
+	}
+}
+
+func TestMarkdownFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (markdownFormatter{}).Format(&buf, testBranches()); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected header, separator and 3 rows, got %d lines", len(lines))
+	}
+	if !strings.Contains(lines[4], `feature/pipe\|name`) {
+		t.Errorf("expected pipe to be escaped, got %q", lines[4])
+	}
+}
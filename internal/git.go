@@ -3,13 +3,16 @@ package internal
 import (
 	"errors"
 	"fmt"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/filesystem"
 )
 
 // Custom error types for better error handling
@@ -17,6 +20,7 @@ var (
 	ErrProtectedBranch = errors.New("protected branch")
 	ErrCurrentBranch   = errors.New("cannot delete current branch")
 	ErrDefaultBranch   = errors.New("cannot delete default branch")
+	ErrBranchChanged   = errors.New("changed since analysis")
 )
 
 // ProtectedBranchError represents an error when trying to delete a protected branch
@@ -32,43 +36,205 @@ func (e *ProtectedBranchError) Is(target error) bool {
 	return target == ErrProtectedBranch
 }
 
+// DefaultRemote is the remote used unless another one is configured
+const DefaultRemote = "origin"
+
 type GitRepo struct {
 	repo          *git.Repository
 	repoPath      string
 	defaultBranch string
+	remote        string
+	jobs          int
+	backend       gitBackend
+	useCache      bool
+	shallow       bool
+	partial       bool
+	bare          bool
+	atomicPush    bool
 }
 
 type Branch struct {
-	Name       string    `json:"name"`
-	IsMerged   bool      `json:"is_merged"`
-	IsStale    bool      `json:"is_stale"`
-	LastCommit time.Time `json:"last_commit"`
-	Protected  bool      `json:"protected"`
+	Name       string    `json:"name" yaml:"name"`
+	Tip        string    `json:"tip" yaml:"tip"`
+	IsMerged   bool      `json:"is_merged" yaml:"is_merged"`
+	IsStale    bool      `json:"is_stale" yaml:"is_stale"`
+	LastCommit time.Time `json:"last_commit" yaml:"last_commit"`
+	Protected  bool      `json:"protected" yaml:"protected"`
+	Author     string    `json:"author" yaml:"author"`
+
+	// Upstream is the configured upstream branch (e.g. "origin/feature-x") and
+	// UpstreamGone is set when that branch no longer exists
+	Upstream     string `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	UpstreamGone bool   `json:"upstream_gone,omitempty" yaml:"upstream_gone,omitempty"`
+
+	// MergeUnknown is set when the branch was not found in the default branch
+	// history but that history is incomplete (shallow or partial clone), so the
+	// branch may still have been merged
+	MergeUnknown bool `json:"merge_unknown,omitempty" yaml:"merge_unknown,omitempty"`
+
+	// Error is set when the branch could not be analyzed, e.g. because its tip
+	// commit is missing from a shallow or partial clone
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
-// NewGitRepo opens a git repository at the given path and detects the default branch.
-// Returns an error if the path is not a valid git repository.
+// Status returns the display status of the branch: merged, stale or active,
+// or unknown if the branch could not be analyzed or is recent but its merge
+// status is unknown. A merged branch is reported as merged even if it is also stale.
+func (b Branch) Status() string {
+	if b.Error != "" {
+		return "unknown"
+	}
+	if b.IsMerged {
+		return "merged"
+	}
+	if b.IsStale {
+		return "stale"
+	}
+	if b.MergeUnknown {
+		return "unknown"
+	}
+	return "active"
+}
+
+// NewGitRepo opens the git repository containing path and detects the default
+// branch. path may be a subdirectory of a working tree, a linked worktree or a
+// bare repository; see FindRepository. Returns an error wrapping
+// ErrNotRepository if no repository is found.
 func NewGitRepo(path string) (*GitRepo, error) {
-	repo, err := git.PlainOpen(path)
+	root, err := FindRepository(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open git repository at %s: %w\nIs this a git repository? Try running 'git status'", path, err)
+		return nil, fmt.Errorf("%w\nTry running this command from within a git repository, or use -C <path>", err)
 	}
 
-	defaultBranch, err := detectDefaultBranch(repo)
+	repo, err := openRepository(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository at %s: %w\nIs this a git repository? Try running 'git status'", root, err)
+	}
+
+	defaultBranch, err := detectDefaultBranch(repo, DefaultRemote)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := newBackend(BackendAuto)
+	if err != nil {
+		return nil, err
+	}
+
+	shallow, err := isShallowClone(repo)
+	if err != nil {
+		return nil, err
+	}
+	partial, err := isPartialClone(repo)
 	if err != nil {
 		return nil, err
 	}
 
 	return &GitRepo{
 		repo:          repo,
-		repoPath:      path,
+		repoPath:      root,
 		defaultBranch: defaultBranch,
+		remote:        DefaultRemote,
+		jobs:          1,
+		backend:       backend,
+		useCache:      true,
+		shallow:       shallow,
+		partial:       partial,
+		bare:          isBare(repo),
 	}, nil
 }
 
-func detectDefaultBranch(repo *git.Repository) (string, error) {
+// SetBackend selects how merge detection and remote deletion are performed
+func (g *GitRepo) SetBackend(name Backend) error {
+	backend, err := newBackend(name)
+	if err != nil {
+		return err
+	}
+	g.backend = backend
+	return nil
+}
+
+// SetRemote selects the remote used to detect the default branch and to
+// delete remote branches. The default branch is detected again from it.
+func (g *GitRepo) SetRemote(name string) error {
+	if _, err := g.repo.Remote(name); err != nil {
+		return fmt.Errorf("remote %s: %w", name, err)
+	}
+	defaultBranch, err := detectDefaultBranch(g.repo, name)
+	if err != nil {
+		return err
+	}
+	g.remote = name
+	g.defaultBranch = defaultBranch
+	return nil
+}
+
+// SetDefaultBranch overrides the detected default branch
+func (g *GitRepo) SetDefaultBranch(name string) error {
+	if _, err := g.repo.Reference(plumbing.NewBranchReferenceName(name), true); err != nil {
+		return fmt.Errorf("default branch %s: %w", name, err)
+	}
+	g.defaultBranch = name
+	return nil
+}
+
+// SetJobs sets the number of branches analyzed concurrently by ListBranches
+func (g *GitRepo) SetJobs(n int) {
+	g.jobs = max(n, 1)
+}
+
+// SetCache enables or disables the merge detection cache stored in the
+// repository's git directory
+func (g *GitRepo) SetCache(enabled bool) {
+	g.useCache = enabled
+}
+
+// Path returns the root of the repository: its working tree, or its git
+// directory if it is bare
+func (g *GitRepo) Path() string {
+	return g.repoPath
+}
+
+// dataDir returns the directory where branch-clean keeps its own files. It is
+// shared by all worktrees of the repository.
+func (g *GitRepo) dataDir() (string, error) {
+	gitDir, err := g.gitDir()
+	if err != nil {
+		return "", err
+	}
+	common, err := commonDir(gitDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(common, "branch-clean"), nil
+}
+
+// gitDir returns the path of the repository's git directory
+func (g *GitRepo) gitDir() (string, error) {
+	storage, ok := g.repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", fmt.Errorf("repository is not stored on disk")
+	}
+	return storage.Filesystem().Root(), nil
+}
+
+// openHandle opens an additional handle on the repository. go-git repositories
+// are not safe for concurrent use, so each analysis worker gets its own.
+func (g *GitRepo) openHandle() (*git.Repository, error) {
+	return openRepository(g.repoPath)
+}
+
+func detectDefaultBranch(repo *git.Repository, remoteName string) (string, error) {
+	// A bare repository is usually the remote itself, and its HEAD names the
+	// default branch
+	if isBare(repo) {
+		if name, ok := headBranch(repo); ok {
+			return name, nil
+		}
+	}
+
 	// First, try to get the default branch from remote HEAD
-	remote, err := repo.Remote("origin")
+	remote, err := repo.Remote(remoteName)
 	if err == nil {
 		refs, listErr := remote.List(&git.ListOptions{})
 		if listErr == nil {
@@ -113,73 +279,247 @@ func detectDefaultBranch(repo *git.Repository) (string, error) {
 	return "", fmt.Errorf("repository has no branches")
 }
 
+// analysisInput holds the data shared by all per-branch analyses of one ListBranches call
+type analysisInput struct {
+	merged         map[string]bool
+	mergeComplete  bool
+	config         *config.Config
+	staleThreshold time.Time
+	protected      []string
+}
+
+// ListBranches analyzes all local branches except the default branch.
+// Branches are analyzed by up to SetJobs workers and returned sorted by name.
+// A failing branch does not stop the analysis of the others: it is returned with
+// its Error field set and an unknown status.
 func (g *GitRepo) ListBranches(staleDays int, protectedPatterns []string) ([]Branch, error) {
 	branchRefs, err := g.repo.Branches()
 	if err != nil {
 		return nil, err
 	}
 
-	var branches []Branch
-	staleThreshold := time.Now().AddDate(0, 0, -staleDays)
-
+	var refs []*plumbing.Reference
 	err = branchRefs.ForEach(func(ref *plumbing.Reference) error {
-		name := ref.Name().Short()
-		if name == g.defaultBranch {
-			return nil
+		if ref.Name().Short() != g.defaultBranch {
+			refs = append(refs, ref)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name() < refs[j].Name()
+	})
 
-		commit, commitErr := g.repo.CommitObject(ref.Hash())
-		if commitErr != nil {
-			return commitErr
-		}
+	merged, mergeComplete, err := g.mergedBranches(refs)
+	if err != nil {
+		return nil, err
+	}
 
-		isMerged, mergeErr := g.isMerged(name)
-		if mergeErr != nil {
-			return mergeErr
-		}
+	cfg, err := g.repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %w", err)
+	}
+
+	input := &analysisInput{
+		merged:         merged,
+		mergeComplete:  mergeComplete,
+		config:         cfg,
+		staleThreshold: time.Now().AddDate(0, 0, -staleDays),
+		protected:      protectedPatterns,
+	}
 
-		branch := Branch{
-			Name:       name,
-			IsMerged:   isMerged,
-			IsStale:    commit.Committer.When.Before(staleThreshold),
-			LastCommit: commit.Committer.When,
-			Protected:  isProtected(name, protectedPatterns),
+	// Open one repository handle per worker before starting any of them
+	handles := []*git.Repository{g.repo}
+	for len(handles) < min(g.jobs, len(refs)) {
+		handle, openErr := g.openHandle()
+		if openErr != nil {
+			return nil, fmt.Errorf("failed to open repository: %w", openErr)
 		}
+		handles = append(handles, handle)
+	}
 
-		branches = append(branches, branch)
-		return nil
-	})
+	branches := make([]Branch, len(refs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for _, handle := range handles {
+		wg.Add(1)
+		go func(repo *git.Repository) {
+			defer wg.Done()
+			for i := range indexes {
+				branch, analyzeErr := analyzeBranch(repo, refs[i], input)
+				if analyzeErr != nil {
+					name := refs[i].Name().Short()
+					branch = Branch{
+						Name:      name,
+						Tip:       refs[i].Hash().String(),
+						Protected: isProtected(name, input.protected),
+						Error:     analyzeErr.Error(),
+					}
+				}
+				branches[i] = branch
+			}
+		}(handle)
+	}
+	for i := range refs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
 
-	return branches, err
+	return branches, nil
 }
 
-// isMerged checks if a branch has been merged into the default branch using git CLI.
-// This handles all merge strategies (merge commits, squash merges, rebase merges) correctly.
-func (g *GitRepo) isMerged(branchName string) (bool, error) {
-	// Use git merge-base --is-ancestor to check if the branch is merged
-	// This works for all merge strategies
-	cmd := exec.Command("git", "merge-base", "--is-ancestor", branchName, g.defaultBranch)
-	cmd.Dir = g.repoPath
+// analyzeBranch computes the status of a single branch. repo must not be used
+// by other goroutines while the analysis runs.
+func analyzeBranch(repo *git.Repository, ref *plumbing.Reference, input *analysisInput) (Branch, error) {
+	name := ref.Name().Short()
 
-	err := cmd.Run()
+	commit, err := repo.CommitObject(ref.Hash())
 	if err != nil {
-		// Exit code 1 means not an ancestor (not merged)
-		// Exit code 0 means it is an ancestor (merged)
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			if exitErr.ExitCode() == 1 {
-				return false, nil
+		return Branch{}, fmt.Errorf("failed to read tip commit: %w", err)
+	}
+
+	branch := Branch{
+		Name:         name,
+		Tip:          ref.Hash().String(),
+		IsMerged:     input.merged[name],
+		MergeUnknown: !input.merged[name] && !input.mergeComplete,
+		IsStale:      commit.Committer.When.Before(input.staleThreshold),
+		LastCommit:   commit.Committer.When,
+		Protected:    isProtected(name, input.protected),
+		Author:       commit.Author.Name,
+	}
+
+	// Resolve the configured upstream and check that it still exists
+	if upstream, ok := input.config.Branches[name]; ok && upstream.Remote != "" && upstream.Merge != "" {
+		trackingRef := plumbing.NewRemoteReferenceName(upstream.Remote, upstream.Merge.Short())
+		branch.Upstream = upstream.Remote + "/" + upstream.Merge.Short()
+		if upstream.Remote == "." {
+			trackingRef = upstream.Merge
+			branch.Upstream = upstream.Merge.Short()
+		}
+
+		if _, refErr := repo.Reference(trackingRef, true); refErr != nil {
+			if !errors.Is(refErr, plumbing.ErrReferenceNotFound) {
+				return Branch{}, fmt.Errorf("failed to resolve upstream %s: %w", branch.Upstream, refErr)
 			}
+			branch.UpstreamGone = true
 		}
-		return false, fmt.Errorf("failed to check merge status: %w", err)
 	}
 
-	return true, nil
+	return branch, nil
+}
+
+// mergedBranches returns the set of the given branches that have been merged
+// into the default branch, as determined by the configured backend, and whether
+// the default branch history was complete. When it was not, a branch missing
+// from the set may still have been merged.
+//
+// Results are cached by branch and default branch tip, so the backend only
+// runs when one of the tips has moved since the last run. Only definitive
+// results are cached.
+func (g *GitRepo) mergedBranches(refs []*plumbing.Reference) (map[string]bool, bool, error) {
+	if !g.useCache {
+		return g.detectMerged()
+	}
+
+	target, err := g.repo.ResolveRevision(plumbing.Revision(g.defaultBranch))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve default branch %s: %w", g.defaultBranch, err)
+	}
+	dir, err := g.dataDir()
+	if err != nil {
+		return g.detectMerged()
+	}
+	cache := loadAnalysisCache(filepath.Join(dir, "cache"))
+
+	merged := make(map[string]bool)
+	cached := true
+	for _, ref := range refs {
+		isMerged, ok := cache.lookup(cacheKey(ref.Hash(), *target, mergeModeAncestor))
+		if !ok {
+			cached = false
+			break
+		}
+		merged[ref.Name().Short()] = isMerged
+	}
+	if cached {
+		return merged, true, nil
+	}
+
+	merged, complete, err := g.detectMerged()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, ref := range refs {
+		if isMerged := merged[ref.Name().Short()]; isMerged || complete {
+			cache.store(cacheKey(ref.Hash(), *target, mergeModeAncestor), isMerged)
+		}
+	}
+
+	// The cache is an optimization only; a read-only repository must still be listable
+	_ = cache.save()
+	return merged, complete, nil
+}
+
+// detectMerged runs merge detection with the configured backend and checks
+// whether the history it relied on was complete
+func (g *GitRepo) detectMerged() (map[string]bool, bool, error) {
+	merged, err := g.backend.mergedBranches(g)
+	if errors.Is(err, errIncompleteHistory) {
+		return merged, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The git binary treats a shallow boundary as the root of history, so
+	// check separately whether the default branch reaches one
+	if g.shallow {
+		complete, err := g.defaultHistoryComplete()
+		if err != nil {
+			return nil, false, err
+		}
+		return merged, complete, nil
+	}
+	return merged, true, nil
 }
 
 // DeleteBranch deletes a branch by name.
 // Returns ErrCurrentBranch if trying to delete the currently checked out branch.
 // Returns ErrDefaultBranch if trying to delete the default branch.
-func (g *GitRepo) DeleteBranch(name string) error {
+// When expectedTip is set, the branch is only deleted if it still points at that
+// commit, so commits made after the analysis are never lost; otherwise
+// ErrBranchChanged is returned.
+func (g *GitRepo) DeleteBranch(name, expectedTip string) error {
+	if err := g.checkDeletable(name); err != nil {
+		return err
+	}
+	return g.backend.deleteBranch(g, name, expectedTip)
+}
+
+// checkTip returns ErrBranchChanged unless the branch still points at expectedTip
+func (g *GitRepo) checkTip(name, expectedTip string) error {
+	ref, err := g.repo.Reference(plumbing.NewBranchReferenceName(name), true)
+	if err != nil {
+		return fmt.Errorf("failed to read branch %s: %w", name, err)
+	}
+	if ref.Hash().String() != expectedTip {
+		return branchChanged(name, ref.Hash(), expectedTip)
+	}
+	return nil
+}
+
+func branchChanged(name string, tip plumbing.Hash, expectedTip string) error {
+	return fmt.Errorf("%w: %s now points at %.8s, analyzed %.8s", ErrBranchChanged, name, tip, expectedTip)
+}
+
+// checkDeletable refuses to remove the current, default or another worktree's
+// checked out branch
+func (g *GitRepo) checkDeletable(name string) error {
 	// Check if trying to delete current branch
 	head, err := g.repo.Head()
 	if err == nil && head.Name().Short() == name {
@@ -191,19 +531,14 @@ func (g *GitRepo) DeleteBranch(name string) error {
 		return fmt.Errorf("%w: '%s'", ErrDefaultBranch, name)
 	}
 
-	return g.repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(name))
-}
-
-// DeleteRemoteBranch deletes a branch from the remote repository.
-func (g *GitRepo) DeleteRemoteBranch(name string) error {
-	cmd := exec.Command("git", "push", "origin", "--delete", name)
-	cmd.Dir = g.repoPath
-
-	output, err := cmd.CombinedOutput()
+	// Like git branch -d, refuse branches checked out in a linked worktree
+	worktree, err := g.worktreeCheckingOut(name)
 	if err != nil {
-		return fmt.Errorf("failed to delete remote branch: %w\nOutput: %s", err, output)
+		return err
+	}
+	if worktree != "" {
+		return fmt.Errorf("%w: '%s' is checked out in worktree %s", ErrCurrentBranch, name, worktree)
 	}
-
 	return nil
 }
 
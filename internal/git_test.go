@@ -1,12 +1,15 @@
 package internal
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
@@ -112,7 +115,7 @@ func TestDeleteBranch(t *testing.T) {
 	))
 
 	gitRepo, _ := NewGitRepo(tmpDir)
-	err := gitRepo.DeleteBranch("test-branch")
+	err := gitRepo.DeleteBranch("test-branch", "")
 
 	if err != nil {
 		t.Errorf("DeleteBranch failed: %v", err)
@@ -128,17 +131,39 @@ func TestDeleteBranch_DefaultBranch(t *testing.T) {
 	tmpDir, _ := setupTestRepo(t)
 	gitRepo, _ := NewGitRepo(tmpDir)
 
-	err := gitRepo.DeleteBranch(gitRepo.defaultBranch)
+	err := gitRepo.DeleteBranch(gitRepo.defaultBranch, "")
 	if err == nil {
 		t.Error("expected error when trying to delete default branch")
 	}
 }
 
+func TestSetDefaultBranch(t *testing.T) {
+	tmpDir, repo := setupTestRepo(t)
+	head, _ := repo.Head()
+	repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("trunk"), head.Hash()))
+
+	gitRepo, _ := NewGitRepo(tmpDir)
+	if err := gitRepo.SetDefaultBranch("missing"); err == nil {
+		t.Error("expected an error for a missing branch")
+	}
+	if err := gitRepo.SetDefaultBranch("trunk"); err != nil {
+		t.Fatalf("SetDefaultBranch failed: %v", err)
+	}
+
+	branches, err := gitRepo.ListBranches(30, nil)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	if len(branches) != 1 || branches[0].Name != "master" || !branches[0].IsMerged {
+		t.Errorf("expected master to be listed as merged into trunk, got %+v", branches)
+	}
+}
+
 func TestDeleteBranch_NonExistent(t *testing.T) {
 	tmpDir, _ := setupTestRepo(t)
 	gitRepo, _ := NewGitRepo(tmpDir)
 
-	err := gitRepo.DeleteBranch("non-existent-branch")
+	err := gitRepo.DeleteBranch("non-existent-branch", "")
 	// Should not panic, but may return an error
 	_ = err
 }
@@ -173,3 +198,310 @@ func TestIsProtected_WildcardPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestBranchStatus(t *testing.T) {
+	tests := []struct {
+		branch Branch
+		want   string
+	}{
+		{Branch{IsMerged: true, IsStale: true}, "merged"},
+		{Branch{IsStale: true}, "stale"},
+		{Branch{}, "active"},
+		{Branch{MergeUnknown: true}, "unknown"},
+		{Branch{MergeUnknown: true, IsStale: true}, "stale"},
+		{Branch{Error: "object not found"}, "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.branch.Status(); got != tt.want {
+			t.Errorf("Status() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// commitOnto writes a commit object with the given parent directly to the object
+// store, reusing the parent's tree. This is much faster than going through the
+// worktree when building large synthetic histories.
+func commitOnto(tb testing.TB, repo *git.Repository, parent *object.Commit, msg string) *object.Commit {
+	tb.Helper()
+
+	sig := object.Signature{Name: "Test", Email: "test@test.com", When: parent.Committer.When.Add(time.Minute)}
+	commit := &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      msg,
+		TreeHash:     parent.TreeHash,
+		ParentHashes: []plumbing.Hash{parent.Hash},
+	}
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		tb.Fatalf("failed to encode commit: %v", err)
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		tb.Fatalf("failed to store commit: %v", err)
+	}
+
+	stored, err := repo.CommitObject(hash)
+	if err != nil {
+		tb.Fatalf("failed to read commit: %v", err)
+	}
+	return stored
+}
+
+// setupSyntheticRepo creates a repository whose default branch has historyLen
+// commits, plus n branches. Even branches point into the default branch history
+// (merged), odd branches carry one extra commit of their own (unmerged).
+func setupSyntheticRepo(tb testing.TB, historyLen, n int) string {
+	tb.Helper()
+
+	dir := tb.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		tb.Fatalf("failed to init repo: %v", err)
+	}
+
+	w, _ := repo.Worktree()
+	if err := os.WriteFile(filepath.Join(dir, "test.txt"), []byte("test"), 0644); err != nil {
+		tb.Fatalf("failed to write test file: %v", err)
+	}
+	w.Add("test.txt")
+	rootHash, err := w.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@test.com", When: time.Now().AddDate(0, 0, -90)},
+	})
+	if err != nil {
+		tb.Fatalf("failed to commit: %v", err)
+	}
+
+	root, _ := repo.CommitObject(rootHash)
+	history := []*object.Commit{root}
+	for i := 1; i < historyLen; i++ {
+		history = append(history, commitOnto(tb, repo, history[i-1], fmt.Sprintf("commit %d", i)))
+	}
+
+	head := history[len(history)-1]
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("master"), head.Hash)); err != nil {
+		tb.Fatalf("failed to update master: %v", err)
+	}
+
+	for i := 0; i < n; i++ {
+		base := history[i%len(history)]
+		tip := base.Hash
+		if i%2 == 1 {
+			tip = commitOnto(tb, repo, base, fmt.Sprintf("branch %d", i)).Hash
+		}
+		ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(fmt.Sprintf("feature/branch-%04d", i)), tip)
+		if err := repo.Storer.SetReference(ref); err != nil {
+			tb.Fatalf("failed to create branch: %v", err)
+		}
+	}
+
+	return dir
+}
+
+func TestListBranches_MergeStatus(t *testing.T) {
+	dir := setupSyntheticRepo(t, 10, 20)
+
+	gitRepo, err := NewGitRepo(dir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+
+	branches, err := gitRepo.ListBranches(30, nil)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	if len(branches) != 20 {
+		t.Fatalf("expected 20 branches, got %d", len(branches))
+	}
+
+	for _, b := range branches {
+		var i int
+		fmt.Sscanf(b.Name, "feature/branch-%d", &i)
+		if want := i%2 == 0; b.IsMerged != want {
+			t.Errorf("%s: IsMerged = %v, want %v", b.Name, b.IsMerged, want)
+		}
+	}
+}
+
+func BenchmarkListBranches(b *testing.B) {
+	for _, n := range []int{100, 1500} {
+		dir := setupSyntheticRepo(b, 200, n)
+		for _, jobs := range []int{1, 8} {
+			b.Run(fmt.Sprintf("branches=%d/jobs=%d", n, jobs), func(b *testing.B) {
+				gitRepo, err := NewGitRepo(dir)
+				if err != nil {
+					b.Fatalf("NewGitRepo failed: %v", err)
+				}
+				gitRepo.SetJobs(jobs)
+				gitRepo.SetCache(false)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := gitRepo.ListBranches(30, nil); err != nil {
+						b.Fatalf("ListBranches failed: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkMergedBranches(b *testing.B) {
+	dir := setupSyntheticRepo(b, 200, 1500)
+
+	for _, backend := range []Backend{BackendExec, BackendGoGit} {
+		b.Run(string(backend), func(b *testing.B) {
+			gitRepo, err := NewGitRepo(dir)
+			if err != nil {
+				b.Fatalf("NewGitRepo failed: %v", err)
+			}
+			if err := gitRepo.SetBackend(backend); err != nil {
+				b.Skipf("backend unavailable: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := gitRepo.backend.mergedBranches(gitRepo); err != nil {
+					b.Fatalf("mergedBranches failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestListBranches_Parallel(t *testing.T) {
+	dir := setupSyntheticRepo(t, 20, 50)
+
+	serial, _ := NewGitRepo(dir)
+	want, err := serial.ListBranches(30, nil)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+
+	parallel, _ := NewGitRepo(dir)
+	parallel.SetJobs(8)
+	got, err := parallel.ListBranches(30, nil)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d branches, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Name != want[i].Name || got[i].IsMerged != want[i].IsMerged || got[i].Tip != want[i].Tip {
+			t.Errorf("branch %d differs: got %+v, want %+v", i, got[i], want[i])
+		}
+		if i > 0 && got[i-1].Name >= got[i].Name {
+			t.Errorf("branches not sorted: %s before %s", got[i-1].Name, got[i].Name)
+		}
+	}
+}
+
+func TestListBranches_BrokenBranches(t *testing.T) {
+	tmpDir, repo := setupTestRepo(t)
+
+	head, _ := repo.Head()
+	missing := plumbing.NewHash("1234567890123456789012345678901234567890")
+	for _, ref := range []*plumbing.Reference{
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("good"), head.Hash()),
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("broken-1"), missing),
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("broken-2"), missing),
+	} {
+		if err := repo.Storer.SetReference(ref); err != nil {
+			t.Fatalf("failed to create branch: %v", err)
+		}
+	}
+
+	gitRepo, _ := NewGitRepo(tmpDir)
+	gitRepo.SetJobs(4)
+	branches, err := gitRepo.ListBranches(30, nil)
+	if err != nil {
+		t.Fatalf("expected broken branches not to fail the listing, got %v", err)
+	}
+	if len(branches) != 3 {
+		t.Fatalf("expected 3 branches, got %+v", branches)
+	}
+
+	for _, b := range branches {
+		broken := strings.HasPrefix(b.Name, "broken-")
+		if broken != (b.Error != "") {
+			t.Errorf("%s: unexpected error %q", b.Name, b.Error)
+		}
+		if broken && (b.Status() != "unknown" || b.Tip != missing.String()) {
+			t.Errorf("%s: status = %s, tip = %s", b.Name, b.Status(), b.Tip)
+		}
+	}
+}
+
+func TestListBranches_Upstream(t *testing.T) {
+	tmpDir, repo := setupTestRepo(t)
+
+	head, _ := repo.Head()
+	for _, name := range []string{"tracked", "gone"} {
+		repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), head.Hash()))
+	}
+	repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "tracked"), head.Hash()))
+
+	cfg, _ := repo.Config()
+	for _, name := range []string{"tracked", "gone"} {
+		cfg.Branches[name] = &config.Branch{Name: name, Remote: "origin", Merge: plumbing.NewBranchReferenceName(name)}
+	}
+	if err := repo.SetConfig(cfg); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	gitRepo, _ := NewGitRepo(tmpDir)
+	branches, err := gitRepo.ListBranches(30, nil)
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+
+	byName := make(map[string]Branch)
+	for _, b := range branches {
+		byName[b.Name] = b
+	}
+	if b := byName["tracked"]; b.Upstream != "origin/tracked" || b.UpstreamGone {
+		t.Errorf("unexpected upstream state for tracked: %+v", b)
+	}
+	if b := byName["gone"]; b.Upstream != "origin/gone" || !b.UpstreamGone {
+		t.Errorf("unexpected upstream state for gone: %+v", b)
+	}
+}
+
+func TestListBranches_Cache(t *testing.T) {
+	dir := setupSyntheticRepo(t, 5, 2)
+
+	gitRepo, err := NewGitRepo(dir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	if _, err := gitRepo.ListBranches(30, nil); err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+
+	cachePath := filepath.Join(dir, ".git", "branch-clean", "cache")
+	cache := loadAnalysisCache(cachePath)
+	if len(cache.Entries) != 2 {
+		t.Fatalf("expected 2 cache entries, got %d", len(cache.Entries))
+	}
+
+	// Flip every cached result so the next run shows whether the cache was used
+	for key, merged := range cache.Entries {
+		cache.store(key, !merged)
+	}
+	if err := cache.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	cached, _ := gitRepo.ListBranches(30, nil)
+	gitRepo.SetCache(false)
+	fresh, _ := gitRepo.ListBranches(30, nil)
+	for i := range fresh {
+		if cached[i].IsMerged == fresh[i].IsMerged {
+			t.Errorf("%s: expected the cached result to be used", fresh[i].Name)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// LockFile is the name of the session lock in the repository's branch-clean directory
+const LockFile = "lock"
+
+// StaleLockAge is the age after which a lock is taken over even if the process
+// holding it still seems to be running
+const StaleLockAge = 24 * time.Hour
+
+// ErrLocked is returned when another session holds the repository lock
+var ErrLocked = errors.New("another branch-clean session is running")
+
+// lockPollInterval is how often a held lock is checked while waiting for it
+var lockPollInterval = 250 * time.Millisecond
+
+// LockInfo identifies the session holding a lock
+type LockInfo struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+}
+
+// Lock is a held repository lock
+type Lock struct {
+	path string
+}
+
+// LockPath returns the lock of the repository, shared by all of its worktrees
+func (g *GitRepo) LockPath() (string, error) {
+	dir, err := g.dataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate lock file: %w", err)
+	}
+	return filepath.Join(dir, LockFile), nil
+}
+
+// AcquireLock creates the lock file at path, waiting up to wait for another
+// session to release it. A lock left behind by a process that is no longer
+// running on this host, or older than StaleLockAge, is taken over.
+func AcquireLock(path string, wait time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err := createLock(path)
+		if err == nil {
+			return &Lock{path: path}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock %s: %w", path, err)
+		}
+
+		holder, err := readLock(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if holder.stale(time.Now()) {
+			if err := removeLock(path, holder); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: pid %d on %s since %s (lock file %s)",
+				ErrLocked, holder.PID, holder.Host, holder.Started.Local().Format("2006-01-02 15:04:05"), path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// Release removes the lock file
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// createLock writes the lock to a temporary file and links it into place, so
+// other sessions never see a partially written lock
+func createLock(path string) error {
+	host, _ := os.Hostname()
+	data, err := json.Marshal(LockInfo{PID: os.Getpid(), Host: host, Started: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), LockFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Link(tmp.Name(), path)
+}
+
+func readLock(path string) (LockInfo, error) {
+	var info LockInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("invalid lock file %s: %w\nRemove it if no branch-clean session is running", path, err)
+	}
+	return info, nil
+}
+
+// removeLock removes a stale lock unless another session replaced it meanwhile
+func removeLock(path string, stale LockInfo) error {
+	current, err := readLock(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && current != stale) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale lock %s: %w", path, err)
+	}
+	return nil
+}
+
+// stale reports whether the session holding the lock is gone
+func (info LockInfo) stale(now time.Time) bool {
+	if now.Sub(info.Started) > StaleLockAge {
+		return true
+	}
+	host, _ := os.Hostname()
+	return info.Host == host && !processAlive(info.PID)
+}
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows, FindProcess fails unless the process exists
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "branch-clean", LockFile)
+
+	lock, err := AcquireLock(path, 0)
+	if err != nil {
+		t.Fatalf("AcquireLock failed: %v", err)
+	}
+	if _, err := AcquireLock(path, 0); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+
+	// A waiting session gets the lock once it is released
+	lockPollInterval = 10 * time.Millisecond
+	defer func() { lockPollInterval = 250 * time.Millisecond }()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock.Release()
+	}()
+	second, err := AcquireLock(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLock with wait failed: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("lock file still exists after release")
+	}
+}
+
+func TestAcquireLock_Stale(t *testing.T) {
+	host, _ := os.Hostname()
+	tests := map[string]LockInfo{
+		"dead process": {PID: 1 << 30, Host: host, Started: time.Now()},
+		"too old":      {PID: os.Getpid(), Host: "elsewhere", Started: time.Now().Add(-StaleLockAge - time.Hour)},
+	}
+
+	for name, info := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), LockFile)
+			data, _ := json.Marshal(info)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			lock, err := AcquireLock(path, 0)
+			if err != nil {
+				t.Fatalf("expected the stale lock to be taken over, got %v", err)
+			}
+			lock.Release()
+		})
+	}
+}
+
+func TestAcquireLock_OtherHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFile)
+	data, _ := json.Marshal(LockInfo{PID: 1 << 30, Host: "elsewhere", Started: time.Now()})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := AcquireLock(path, 0); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected a recent lock from another host to be honored, got %v", err)
+	}
+}
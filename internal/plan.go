@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// PlanVersion is the version of the plan file format
+const PlanVersion = 1
+
+// Plan is a reviewable list of branches to clean up, written by the plan
+// command and carried out by apply. Each branch is recorded with its tip so
+// that apply only deletes branches that did not change in between.
+type Plan struct {
+	Version       int             `json:"version"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Repository    string          `json:"repository"`
+	DefaultBranch string          `json:"default_branch"`
+	DeleteRemote  bool            `json:"delete_remote"`
+	Archive       ArchiveMode     `json:"archive,omitempty"`
+	Branches      []PlannedBranch `json:"branches"`
+}
+
+// PlannedBranch is a branch selected for cleanup in a plan
+type PlannedBranch struct {
+	Name       string    `json:"name"`
+	Tip        string    `json:"tip"`
+	Status     string    `json:"status"`
+	LastCommit time.Time `json:"last_commit"`
+}
+
+// NewPlan returns a plan to clean up branches of the repository
+func (g *GitRepo) NewPlan(branches []Branch, deleteRemote bool, archive ArchiveMode, now time.Time) *Plan {
+	plan := &Plan{
+		Version:       PlanVersion,
+		CreatedAt:     now,
+		Repository:    g.repoPath,
+		DefaultBranch: g.defaultBranch,
+		DeleteRemote:  deleteRemote,
+		Archive:       archive,
+		Branches:      []PlannedBranch{},
+	}
+	for _, b := range branches {
+		plan.Branches = append(plan.Branches, PlannedBranch{Name: b.Name, Tip: b.Tip, Status: b.Status(), LastCommit: b.LastCommit})
+	}
+	return plan
+}
+
+// WritePlan writes plan as indented JSON
+func WritePlan(w io.Writer, plan *Plan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(plan); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// LoadPlan reads and validates a plan file
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if plan.Version != PlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d in %s (expected %d)", plan.Version, path, PlanVersion)
+	}
+	if plan.Archive != "" {
+		if _, err := ParseArchiveMode(string(plan.Archive)); err != nil {
+			return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, b := range plan.Branches {
+		if b.Name == "" || !isFullHash(b.Tip) {
+			return nil, fmt.Errorf("invalid plan %s: branch %d needs a name and a full tip SHA", path, i+1)
+		}
+		if seen[b.Name] {
+			return nil, fmt.Errorf("invalid plan %s: branch %s is listed twice", path, b.Name)
+		}
+		seen[b.Name] = true
+	}
+	return &plan, nil
+}
+
+// CheckRepository returns an error unless the plan was made for this
+// repository: its default branch must be the same, and so must the repository
+// if the plan records it
+func (p *Plan) CheckRepository(g *GitRepo) error {
+	if p.DefaultBranch != g.defaultBranch {
+		return fmt.Errorf("plan was made for default branch %s, but the repository's default branch is %s", p.DefaultBranch, g.defaultBranch)
+	}
+	if p.Repository == "" || p.Repository == g.repoPath {
+		return nil
+	}
+	planned, err := os.Stat(p.Repository)
+	if err == nil {
+		var current os.FileInfo
+		if current, err = os.Stat(g.repoPath); err == nil && os.SameFile(planned, current) {
+			return nil
+		}
+	}
+	return fmt.Errorf("plan was made for repository %s, not %s", p.Repository, g.repoPath)
+}
+
+// isFullHash reports whether s is a full SHA-1 or SHA-256 object name
+func isFullHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
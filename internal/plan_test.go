@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlan_RoundTrip(t *testing.T) {
+	tmpDir, _ := setupTestRepo(t)
+	gitRepo, err := NewGitRepo(tmpDir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+
+	tip := strings.Repeat("a", 40)
+	branches := []Branch{{Name: "feature", Tip: tip, IsMerged: true}}
+	plan := gitRepo.NewPlan(branches, true, ArchiveTag, time.Now())
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePlan(f, plan); err != nil {
+		t.Fatalf("WritePlan failed: %v", err)
+	}
+	f.Close()
+
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan failed: %v", err)
+	}
+	if loaded.Repository != tmpDir || loaded.DefaultBranch != "master" || !loaded.DeleteRemote || loaded.Archive != ArchiveTag {
+		t.Errorf("unexpected plan: %+v", loaded)
+	}
+	if len(loaded.Branches) != 1 || loaded.Branches[0].Name != "feature" || loaded.Branches[0].Tip != tip || loaded.Branches[0].Status != "merged" {
+		t.Errorf("unexpected branches: %+v", loaded.Branches)
+	}
+}
+
+func TestLoadPlan_Invalid(t *testing.T) {
+	tip := strings.Repeat("a", 40)
+	tests := map[string]string{
+		"version":   `{"version": 2, "branches": []}`,
+		"short tip": `{"version": 1, "branches": [{"name": "a", "tip": "abc"}]}`,
+		"not hex":   `{"version": 1, "branches": [{"name": "a", "tip": "` + strings.Repeat("z", 40) + `"}]}`,
+		"no name":   `{"version": 1, "branches": [{"tip": "` + tip + `"}]}`,
+		"duplicate": `{"version": 1, "branches": [{"name": "a", "tip": "` + tip + `"}, {"name": "a", "tip": "` + tip + `"}]}`,
+		"archive":   `{"version": 1, "archive": "zip", "branches": []}`,
+		"json":      `{"version": 1,`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plan.json")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadPlan(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadPlan_SHA256(t *testing.T) {
+	tip := strings.Repeat("b", 64)
+	path := filepath.Join(t.TempDir(), "plan.json")
+	content := `{"version": 1, "branches": [{"name": "a", "tip": "` + tip + `"}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan failed: %v", err)
+	}
+	if plan.Branches[0].Tip != tip {
+		t.Errorf("unexpected tip %s", plan.Branches[0].Tip)
+	}
+}
+
+func TestPlan_CheckRepository(t *testing.T) {
+	tmpDir, _ := setupTestRepo(t)
+	gitRepo, err := NewGitRepo(tmpDir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	otherDir, _ := setupTestRepo(t)
+
+	tests := []struct {
+		name string
+		plan Plan
+		ok   bool
+	}{
+		{"same", Plan{Repository: tmpDir, DefaultBranch: "master"}, true},
+		{"no repository", Plan{DefaultBranch: "master"}, true},
+		{"default branch", Plan{Repository: tmpDir, DefaultBranch: "main"}, false},
+		{"repository", Plan{Repository: otherDir, DefaultBranch: "master"}, false},
+		{"missing repository", Plan{Repository: filepath.Join(tmpDir, "gone"), DefaultBranch: "master"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.plan.CheckRepository(gitRepo); (err == nil) != tt.ok {
+			t.Errorf("%s: CheckRepository() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
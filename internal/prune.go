@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// TrackingRef is a remote-tracking ref of the configured remote
+type TrackingRef struct {
+	// Name is the short name, e.g. origin/feature-x, and Branch the name of
+	// the branch on the remote
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	Ref    string `json:"ref"`
+	Tip    string `json:"tip"`
+}
+
+// StaleTrackingRefs returns the remote-tracking refs of the configured remote
+// whose branch is not in remoteTips, as returned by RemoteBranchTips. Only
+// tracking refs the remote's fetch refspecs map are considered.
+func (g *GitRepo) StaleTrackingRefs(remoteTips map[string]string) ([]TrackingRef, error) {
+	remote, err := g.repo.Remote(g.remote)
+	if err != nil {
+		return nil, fmt.Errorf("remote %s: %w", g.remote, err)
+	}
+	refs, err := g.trackingRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []TrackingRef
+	for _, ref := range refs {
+		branch, mapped := fetchedBranch(remote.Config().Fetch, ref.Ref)
+		if !mapped {
+			continue
+		}
+		if _, ok := remoteTips[branch]; !ok {
+			ref.Branch = branch
+			stale = append(stale, ref)
+		}
+	}
+	return stale, nil
+}
+
+// OfflineStaleTrackingRefs is StaleTrackingRefs without contacting the remote:
+// the remote's branches are those recorded in FETCH_HEAD by the last fetch.
+// FETCH_HEAD is only trusted if that fetch used the remote's configured
+// refspec; a fetch of named branches, such as git fetch origin main, lists
+// just those. Only tracking refs the refspec maps are considered, and those
+// updated after the fetch, e.g. by a push, are kept.
+func (g *GitRepo) OfflineStaleTrackingRefs() ([]TrackingRef, error) {
+	gitDir, err := g.gitDir()
+	if err != nil {
+		return nil, err
+	}
+	common, err := commonDir(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := g.repo.Remote(g.remote)
+	if err != nil {
+		return nil, fmt.Errorf("remote %s: %w", g.remote, err)
+	}
+
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("remote %s has no URL", g.remote)
+	}
+
+	fetchHead := filepath.Join(gitDir, "FETCH_HEAD")
+	info, err := os.Stat(fetchHead)
+	if err != nil {
+		return nil, fmt.Errorf("no FETCH_HEAD to prune from: %w\nRun git fetch %s first, or prune without --offline", err, g.remote)
+	}
+	branches, err := readFetchHead(fetchHead, urls[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("FETCH_HEAD has no branches of %s\nRun git fetch %s first, or prune without --offline", g.remote, g.remote)
+	}
+	if !g.fullFetch(branches) {
+		return nil, fmt.Errorf("FETCH_HEAD is not from a full fetch of %s, so it may not list every branch\nRun git fetch %s first, or prune without --offline", g.remote, g.remote)
+	}
+
+	refs, err := g.trackingRefs()
+	if err != nil {
+		return nil, err
+	}
+	var stale []TrackingRef
+	for _, ref := range refs {
+		branch, mapped := fetchedBranch(remote.Config().Fetch, ref.Ref)
+		if !mapped {
+			continue
+		}
+		if _, fetched := branches[branch]; fetched {
+			continue
+		}
+		// A ref written after the fetch, e.g. by git push, is not in FETCH_HEAD
+		if loose, statErr := os.Stat(filepath.Join(common, filepath.FromSlash(ref.Ref))); statErr == nil && loose.ModTime().After(info.ModTime()) {
+			continue
+		}
+		ref.Branch = branch
+		stale = append(stale, ref)
+	}
+	return stale, nil
+}
+
+// fullFetch reports whether branches, as read from FETCH_HEAD, come from a
+// fetch of the configured refspec. Such a fetch marks every branch
+// not-for-merge except the upstream of the current branch, while branches
+// named on the command line are all for merge. A FETCH_HEAD holding only the
+// upstream is ambiguous, as git pull origin main writes the same, and is not
+// trusted.
+func (g *GitRepo) fullFetch(branches map[string]bool) bool {
+	upstream := g.upstreamBranch()
+	notForMerge := false
+	for name, forMerge := range branches {
+		if !forMerge {
+			notForMerge = true
+		} else if name != upstream {
+			return false
+		}
+	}
+	return notForMerge
+}
+
+// upstreamBranch returns the name, on the configured remote, of the branch the
+// current branch merges from, or "" if it has none there
+func (g *GitRepo) upstreamBranch() string {
+	head, err := g.repo.Head()
+	if err != nil || !head.Name().IsBranch() {
+		return ""
+	}
+	cfg, err := g.repo.Config()
+	if err != nil {
+		return ""
+	}
+	branch, ok := cfg.Branches[head.Name().Short()]
+	if !ok || branch.Remote != g.remote || !branch.Merge.IsBranch() {
+		return ""
+	}
+	return branch.Merge.Short()
+}
+
+// fetchedBranch returns the remote branch that the fetch refspecs map to the
+// remote-tracking ref, and false if none of them does
+func fetchedBranch(refspecs []config.RefSpec, ref string) (string, bool) {
+	for _, spec := range refspecs {
+		// Reverse keeps the force marker on the side that becomes the destination
+		reverse := config.RefSpec(strings.TrimPrefix(spec.String(), "+")).Reverse()
+		if !reverse.Match(plumbing.ReferenceName(ref)) {
+			continue
+		}
+		if src := reverse.Dst(plumbing.ReferenceName(ref)); src.IsBranch() {
+			return src.Short(), true
+		}
+	}
+	return "", false
+}
+
+// DeleteTrackingRef removes a remote-tracking ref
+func (g *GitRepo) DeleteTrackingRef(ref TrackingRef) error {
+	if err := g.repo.Storer.RemoveReference(plumbing.ReferenceName(ref.Ref)); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", ref.Name, err)
+	}
+	return nil
+}
+
+// trackingRefs returns the remote-tracking refs of the configured remote,
+// sorted by name. The symbolic HEAD ref is not included.
+func (g *GitRepo) trackingRefs() ([]TrackingRef, error) {
+	iter, err := g.repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+
+	prefix := "refs/remotes/" + g.remote + "/"
+	var refs []TrackingRef
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if ref.Type() == plumbing.HashReference && strings.HasPrefix(name, prefix) {
+			branch := strings.TrimPrefix(name, prefix)
+			refs = append(refs, TrackingRef{Name: g.remote + "/" + branch, Branch: branch, Ref: name, Tip: ref.Hash().String()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name < refs[j].Name
+	})
+	return refs, nil
+}
+
+// readFetchHead returns the branches that FETCH_HEAD lists for the remote at
+// url, each mapped to whether it is marked for merge. Each line reads
+// "<sha>\t[not-for-merge]\tbranch '<name>' of <url>".
+func readFetchHead(path, url string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FETCH_HEAD: %w", err)
+	}
+	defer f.Close()
+
+	branches := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		description, from, found := strings.Cut(fields[2], " of ")
+		if !found || fetchURL(from) != fetchURL(url) {
+			continue
+		}
+		if name, ok := strings.CutPrefix(description, "branch '"); ok {
+			branches[strings.TrimSuffix(name, "'")] = fields[1] != "not-for-merge"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read FETCH_HEAD: %w", err)
+	}
+	return branches, nil
+}
+
+// fetchURL returns url as git records it in FETCH_HEAD: without credentials
+// and without a trailing slash or .git
+func fetchURL(url string) string {
+	if scheme, rest, found := strings.Cut(url, "://"); found {
+		host, path, _ := strings.Cut(rest, "/")
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		url = scheme + "://" + host + "/" + path
+	}
+	url = strings.TrimSuffix(url, "/")
+	url = strings.TrimSuffix(url, ".git")
+	return strings.TrimSuffix(url, "/")
+}
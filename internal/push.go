@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ErrRemoteNotMerged is returned for remote branches that have commits which
+// were neither analyzed nor merged into the default branch
+var ErrRemoteNotMerged = errors.New("remote branch has unmerged commits")
+
+// ErrRemoteBranchNotFound is returned for remote deletions of branches that
+// are not on the remote
+var ErrRemoteBranchNotFound = errors.New("remote branch not found")
+
+// pushAttempts is how often a remote operation is tried before giving up
+// on a transient failure
+const pushAttempts = 4
+
+// pushBackoff is the delay before the first retry; it doubles for each one after
+var pushBackoff = time.Second
+
+// transientPushErrors are fragments of push and ls-remote errors that are
+// worth retrying: network failures, rate limits and overloaded servers
+var transientPushErrors = []string{
+	"timeout",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"temporarily unavailable",
+	"hung up unexpectedly",
+	"early eof",
+	"could not resolve host",
+	"too many requests",
+	"rate limit",
+}
+
+// transientHTTPStatus matches the HTTP status codes worth retrying as git and
+// servers report them, e.g. "returned error: 503" or "HTTP 429". Bare numbers
+// are not matched since they also occur in hashes and paths.
+var transientHTTPStatus = regexp.MustCompile(`(?:error:?|http(?:/[0-9.]+)?|status:?) *(?:429|502|503|504)\b`)
+
+// RemoteDeletion is a remote branch to delete. When ExpectedTip is set, the
+// branch is only deleted if it still points at that commit on the remote.
+type RemoteDeletion struct {
+	Name        string
+	ExpectedTip string
+}
+
+// SetAtomicPush makes remote deletions all-or-nothing: if any branch cannot be
+// deleted, none of them are
+func (g *GitRepo) SetAtomicPush(atomic bool) {
+	g.atomicPush = atomic
+}
+
+// DeleteRemoteBranches deletes branches from the remote in a single push and
+// returns the result for each of them, nil if it was deleted. A branch that
+// moved on the remote fails with ErrBranchChanged. Pushes that fail as a whole
+// with a transient error are retried with exponential backoff.
+func (g *GitRepo) DeleteRemoteBranches(deletions []RemoteDeletion) []error {
+	results := make([]error, len(deletions))
+	if len(deletions) == 0 {
+		return results
+	}
+
+	var refResults []error
+	attempts := 0
+	err := withRetries(func() (err error) {
+		attempts++
+		refResults, err = g.backend.deleteRemoteBranches(g, deletions)
+		return err
+	})
+	if err != nil {
+		for i := range results {
+			results[i] = fmt.Errorf("failed to delete remote branch: %w", err)
+		}
+		return results
+	}
+	if attempts > 1 {
+		g.resolveRetriedDeletions(deletions, refResults)
+	}
+	return refResults
+}
+
+// resolveRetriedDeletions corrects the results of a retried push. The server
+// may have applied an attempt that failed on the way back, so a branch that
+// seems to have changed was deleted if it is gone from the remote.
+func (g *GitRepo) resolveRetriedDeletions(deletions []RemoteDeletion, results []error) {
+	var tips map[string]string
+	for i, d := range deletions {
+		if !errors.Is(results[i], ErrBranchChanged) && !errors.Is(results[i], ErrRemoteBranchNotFound) {
+			continue
+		}
+		if tips == nil {
+			var err error
+			if tips, err = g.backend.remoteBranchTips(g); err != nil {
+				return
+			}
+		}
+		if _, exists := tips[d.Name]; exists {
+			continue
+		}
+		results[i] = nil
+		if err := g.repo.Storer.RemoveReference(plumbing.NewRemoteReferenceName(g.remote, d.Name)); err != nil {
+			results[i] = fmt.Errorf("failed to remove remote-tracking branch: %w", err)
+		}
+	}
+}
+
+// RemoteBranchTips returns the tip of every branch on the remote, as listed by
+// git ls-remote
+func (g *GitRepo) RemoteBranchTips() (map[string]string, error) {
+	var tips map[string]string
+	err := withRetries(func() (err error) {
+		tips, err = g.backend.remoteBranchTips(g)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote branches: %w", err)
+	}
+	return tips, nil
+}
+
+// CheckRemoteBranch returns ErrRemoteNotMerged unless the remote branch may be
+// deleted along with the local branch b: its remote tip must be the tip that
+// was analyzed, or be merged into the default branch
+func (g *GitRepo) CheckRemoteBranch(b Branch, remoteTip string) error {
+	if remoteTip == b.Tip {
+		return nil
+	}
+	if _, err := g.repo.CommitObject(plumbing.NewHash(remoteTip)); err != nil {
+		return fmt.Errorf("%w: remote branch %s points at %.8s, which is not in this clone (fetch to review it)", ErrRemoteNotMerged, b.Name, remoteTip)
+	}
+	merged, err := g.backend.isAncestor(g, remoteTip, g.defaultBranch)
+	if err != nil {
+		return fmt.Errorf("failed to check remote branch %s: %w", b.Name, err)
+	}
+	if !merged {
+		return fmt.Errorf("%w: remote branch %s points at %.8s, which is not merged into %s", ErrRemoteNotMerged, b.Name, remoteTip, g.defaultBranch)
+	}
+	return nil
+}
+
+// withRetries runs push until it succeeds, fails with an error that is not
+// transient, or has been tried pushAttempts times, backing off in between
+func withRetries(push func() error) error {
+	backoff := pushBackoff
+	for attempt := 1; ; attempt++ {
+		err := push()
+		if err == nil || attempt == pushAttempts || !isTransientPushError(err) {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func isTransientPushError(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, fragment := range transientPushErrors {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return transientHTTPStatus.MatchString(message)
+}
+
+// remoteBranchChanged is the result of a lease that no longer holds
+func remoteBranchChanged(d RemoteDeletion) error {
+	return fmt.Errorf("%w: remote branch %s no longer points at %.8s", ErrBranchChanged, d.Name, d.ExpectedTip)
+}
+
+// remoteBranchNotFound is the result of deleting a branch the remote does not have
+func remoteBranchNotFound(d RemoteDeletion) error {
+	return fmt.Errorf("%w: %s", ErrRemoteBranchNotFound, d.Name)
+}
+
+// parsePushPorcelain returns the result of each deletion from the output of
+// git push --porcelain. ok is false if the output does not cover every
+// deletion, i.e. the push itself failed.
+func parsePushPorcelain(output string, deletions []RemoteDeletion) (results []error, ok bool) {
+	// Each ref is reported as "<flag>\t<from>:<to>\t<summary> (<reason>)"
+	statuses := make(map[string][2]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || len(fields[0]) != 1 {
+			continue
+		}
+		_, to, found := strings.Cut(fields[1], ":")
+		if found {
+			statuses[to] = [2]string{fields[0], fields[2]}
+		}
+	}
+
+	results = make([]error, len(deletions))
+	for i, d := range deletions {
+		status, found := statuses[plumbing.NewBranchReferenceName(d.Name).String()]
+		if !found {
+			return nil, false
+		}
+		flag, summary := status[0], status[1]
+		switch {
+		case flag != "!":
+			results[i] = nil
+		case strings.Contains(summary, "stale info"):
+			results[i] = remoteBranchChanged(d)
+		case strings.Contains(summary, "remote ref does not exist"):
+			results[i] = remoteBranchNotFound(d)
+		default:
+			results[i] = errors.New("failed to delete remote branch: " + summary)
+		}
+	}
+	return results, true
+}
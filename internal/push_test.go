@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestParsePushPorcelain(t *testing.T) {
+	deletions := []RemoteDeletion{{Name: "a"}, {Name: "b", ExpectedTip: "5555"}, {Name: "c"}, {Name: "d"}}
+	output := "To /srv/repo.git\n" +
+		"-\t:refs/heads/a\t[deleted]\n" +
+		"!\t(delete):refs/heads/b\t[rejected] (stale info)\n" +
+		"!\t(delete):refs/heads/c\t[remote rejected] (pre-receive hook declined)\n" +
+		"!\t(delete):refs/heads/d\t[remote rejected] (remote ref does not exist)\n" +
+		"Done\n"
+
+	results, ok := parsePushPorcelain(output, deletions)
+	if !ok {
+		t.Fatal("expected every branch to be reported")
+	}
+	if results[0] != nil {
+		t.Errorf("a: expected success, got %v", results[0])
+	}
+	if !errors.Is(results[1], ErrBranchChanged) {
+		t.Errorf("b: expected ErrBranchChanged, got %v", results[1])
+	}
+	if results[2] == nil || errors.Is(results[2], ErrBranchChanged) {
+		t.Errorf("c: expected a rejection, got %v", results[2])
+	}
+	if !errors.Is(results[3], ErrRemoteBranchNotFound) {
+		t.Errorf("d: expected ErrRemoteBranchNotFound, got %v", results[3])
+	}
+
+	if _, ok := parsePushPorcelain("-\t:refs/heads/a\t[deleted]\n", deletions); ok {
+		t.Error("expected missing branches to be detected")
+	}
+}
+
+func TestIsTransientPushError(t *testing.T) {
+	tests := map[string]bool{
+		"fatal: the remote end hung up unexpectedly":                           true,
+		"ssh: connect to host example.com: Connection timed out":               true,
+		"remote: API rate limit exceeded":                                      true,
+		"The requested URL returned error: 503":                                true,
+		"error: 502 Bad Gateway":                                               true,
+		"unexpected HTTP 429 from server":                                      true,
+		"unexpected http/1.1 504 response":                                     true,
+		"cannot lock ref 'refs/heads/a': is at 5031c2e0 but expected 429e7f1d": false,
+		"'/srv/502/repo.git' does not appear to be a git repository":           false,
+		"Permission denied (publickey)":                                        false,
+		"'/srv/repo' does not appear to be a git repository":                   false,
+	}
+	for message, want := range tests {
+		if got := isTransientPushError(errors.New(message)); got != want {
+			t.Errorf("isTransientPushError(%q) = %v, want %v", message, got, want)
+		}
+	}
+}
+
+// flakyBackend fails remote deletions with a transient error a number of times
+type flakyBackend struct {
+	execBackend
+	failures *int
+}
+
+func (b flakyBackend) deleteRemoteBranches(g *GitRepo, deletions []RemoteDeletion) ([]error, error) {
+	if *b.failures > 0 {
+		*b.failures--
+		return nil, errors.New("fatal: the remote end hung up unexpectedly")
+	}
+	return make([]error, len(deletions)), nil
+}
+
+func TestDeleteRemoteBranches_Retry(t *testing.T) {
+	tmpDir, _ := setupTestRepo(t)
+	gitRepo, _ := NewGitRepo(tmpDir)
+
+	pushBackoff = time.Millisecond
+	defer func() { pushBackoff = time.Second }()
+
+	failures := 2
+	gitRepo.backend = flakyBackend{failures: &failures}
+	if results := gitRepo.DeleteRemoteBranches([]RemoteDeletion{{Name: "a"}}); results[0] != nil {
+		t.Errorf("expected success after retries, got %v", results[0])
+	}
+
+	failures = pushAttempts
+	if results := gitRepo.DeleteRemoteBranches([]RemoteDeletion{{Name: "a"}}); results[0] == nil {
+		t.Error("expected an error once all attempts failed")
+	}
+}
+
+// lostReplyBackend applies remote deletions but reports a transient error, as
+// when the connection drops after the server has updated its refs
+type lostReplyBackend struct {
+	gitBackend
+	failures *int
+}
+
+func (b lostReplyBackend) deleteRemoteBranches(g *GitRepo, deletions []RemoteDeletion) ([]error, error) {
+	results, err := b.gitBackend.deleteRemoteBranches(g, deletions)
+	if err == nil && *b.failures > 0 {
+		*b.failures--
+		return nil, errors.New("fatal: the remote end hung up unexpectedly")
+	}
+	return results, err
+}
+
+func TestDeleteRemoteBranches_RetryAfterApplied(t *testing.T) {
+	pushBackoff = time.Millisecond
+	defer func() { pushBackoff = time.Second }()
+
+	for _, backend := range []gitBackend{execBackend{}, goGitBackend{}} {
+		localDir, local, remote := setupRemoteRepo(t, "a")
+		head, _ := local.Head()
+
+		gitRepo, _ := NewGitRepo(localDir)
+		failures := 1
+		gitRepo.backend = lostReplyBackend{gitBackend: backend, failures: &failures}
+
+		results := gitRepo.DeleteRemoteBranches([]RemoteDeletion{{Name: "a", ExpectedTip: head.Hash().String()}})
+		if results[0] != nil {
+			t.Errorf("%T: expected the branch deleted by the first attempt to count as deleted, got %v", backend, results[0])
+		}
+		if _, err := remote.Reference(plumbing.NewBranchReferenceName("a"), true); err == nil {
+			t.Errorf("%T: branch still exists on the remote", backend)
+		}
+		if _, err := local.Reference(plumbing.NewRemoteReferenceName("origin", "a"), true); err == nil {
+			t.Errorf("%T: remote-tracking branch still exists", backend)
+		}
+	}
+}
+
+func TestDeleteRemoteBranches(t *testing.T) {
+	for _, backend := range []Backend{BackendExec, BackendGoGit} {
+		for _, atomic := range []bool{false, true} {
+			name := string(backend)
+			if atomic {
+				name += "/atomic"
+			}
+			t.Run(name, func(t *testing.T) {
+				localDir, local, remote := setupRemoteRepo(t, "a", "b", "c")
+
+				gitRepo, _ := NewGitRepo(localDir)
+				if err := gitRepo.SetBackend(backend); err != nil {
+					t.Fatalf("SetBackend failed: %v", err)
+				}
+				gitRepo.SetAtomicPush(atomic)
+
+				// b moves on the remote after the analysis
+				head, _ := local.Head()
+				parent, _ := local.CommitObject(head.Hash())
+				moved := commitOnto(t, local, parent, "pushed by someone else")
+				spec := config.RefSpec(moved.Hash.String() + ":refs/heads/b")
+				if err := local.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: []config.RefSpec{spec}, Force: true}); err != nil {
+					t.Fatalf("failed to push: %v", err)
+				}
+
+				tip := head.Hash().String()
+				results := gitRepo.DeleteRemoteBranches([]RemoteDeletion{
+					{Name: "a", ExpectedTip: tip},
+					{Name: "b", ExpectedTip: tip},
+					{Name: "c"},
+				})
+				if !errors.Is(results[1], ErrBranchChanged) {
+					t.Errorf("b: expected ErrBranchChanged, got %v", results[1])
+				}
+
+				for i, name := range []string{"a", "c"} {
+					result := results[i*2]
+					_, refErr := remote.Reference(plumbing.NewBranchReferenceName(name), true)
+					if atomic {
+						if result == nil || refErr != nil {
+							t.Errorf("%s: expected the atomic push to keep it, got %v", name, result)
+						}
+					} else if result != nil || refErr == nil {
+						t.Errorf("%s: expected it to be deleted, got %v", name, result)
+					}
+				}
+				if _, err := remote.Reference(plumbing.NewBranchReferenceName("b"), true); err != nil {
+					t.Error("b was deleted from the remote")
+				}
+			})
+		}
+	}
+}
+
+func TestCheckRemoteBranch(t *testing.T) {
+	for _, backend := range []Backend{BackendExec, BackendGoGit} {
+		t.Run(string(backend), func(t *testing.T) {
+			localDir, local, remote := setupRemoteRepo(t, "same", "merged", "ahead", "unfetched")
+
+			head, _ := local.Head()
+			parent, _ := local.CommitObject(head.Hash())
+			merged := commitOnto(t, local, parent, "merged into master")
+			ahead := commitOnto(t, local, parent, "not merged")
+			setRef := func(repo *git.Repository, name string, hash plumbing.Hash) {
+				if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), hash)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			setRef(local, "master", merged.Hash)
+			spec := []config.RefSpec{
+				config.RefSpec(merged.Hash.String() + ":refs/heads/merged"),
+				config.RefSpec(ahead.Hash.String() + ":refs/heads/ahead"),
+			}
+			if err := local.Push(&git.PushOptions{RemoteName: "origin", RefSpecs: spec, Force: true}); err != nil {
+				t.Fatalf("failed to push: %v", err)
+			}
+			remoteParent, _ := remote.CommitObject(head.Hash())
+			unfetched := commitOnto(t, remote, remoteParent, "only on the remote")
+			setRef(remote, "unfetched", unfetched.Hash)
+
+			gitRepo, _ := NewGitRepo(localDir)
+			if err := gitRepo.SetBackend(backend); err != nil {
+				t.Fatalf("SetBackend failed: %v", err)
+			}
+			tips, err := gitRepo.RemoteBranchTips()
+			if err != nil {
+				t.Fatalf("RemoteBranchTips failed: %v", err)
+			}
+			if len(tips) != 5 || tips["ahead"] != ahead.Hash.String() || tips["unfetched"] != unfetched.Hash.String() {
+				t.Errorf("unexpected remote tips: %v", tips)
+			}
+
+			tests := map[string]bool{"same": true, "merged": true, "ahead": false, "unfetched": false}
+			for name, ok := range tests {
+				err := gitRepo.CheckRemoteBranch(Branch{Name: name, Tip: head.Hash().String()}, tips[name])
+				if ok && err != nil {
+					t.Errorf("%s: expected it to be deletable, got %v", name, err)
+				}
+				if !ok && !errors.Is(err, ErrRemoteNotMerged) {
+					t.Errorf("%s: expected ErrRemoteNotMerged, got %v", name, err)
+				}
+			}
+		})
+	}
+}
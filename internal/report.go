@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed templates/report.html.tmpl
+var reportTemplates embed.FS
+
+// RepoReport holds the branches of one repository included in a report.
+// Error is set instead of Branches when the repository could not be analyzed.
+type RepoReport struct {
+	Name     string   `json:"name"`
+	Path     string   `json:"path"`
+	Branches []Branch `json:"branches"`
+	Stats    Stats    `json:"stats"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// HTMLReport is the data rendered by WriteHTMLReport. Stats aggregates the
+// branches of all repositories.
+type HTMLReport struct {
+	Title        string       `json:"title"`
+	GeneratedAt  time.Time    `json:"generated_at"`
+	Repositories []RepoReport `json:"repositories"`
+	Stats        Stats        `json:"stats"`
+}
+
+// NewHTMLReport builds a report over one or more repositories
+func NewHTMLReport(title string, repos []RepoReport, now time.Time) HTMLReport {
+	var all []Branch
+	for i := range repos {
+		repos[i].Stats = ComputeStats(repos[i].Branches, now)
+		all = append(all, repos[i].Branches...)
+	}
+
+	return HTMLReport{
+		Title:        title,
+		GeneratedAt:  now,
+		Repositories: repos,
+		Stats:        ComputeStats(all, now),
+	}
+}
+
+var reportFuncs = template.FuncMap{
+	"days": func(t time.Time) int {
+		return int(time.Since(t).Hours() / 24)
+	},
+	"maxBucket": func(buckets []AgeBucket) int {
+		m := 0
+		for _, b := range buckets {
+			m = max(m, b.Count)
+		}
+		return m
+	},
+	"percent": func(n, total int) int {
+		if total == 0 {
+			return 0
+		}
+		return n * 100 / total
+	},
+	"reclaimable": func(b Branch) bool {
+		return len(FilterBranches([]Branch{b}, false, false)) == 1
+	},
+	"shortHash": func(hash string) string {
+		if len(hash) > 8 {
+			return hash[:8]
+		}
+		return hash
+	},
+}
+
+// WriteHTMLReport renders report as a self-contained HTML page with a sortable,
+// filterable branch table, an age histogram and a per-author breakdown
+func WriteHTMLReport(w io.Writer, report HTMLReport) error {
+	tmpl, err := template.New("report.html.tmpl").Funcs(reportFuncs).ParseFS(reportTemplates, "templates/report.html.tmpl")
+	if err != nil {
+		return fmt.Errorf("failed to parse report template: %w", err)
+	}
+
+	if err := tmpl.Execute(w, report); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
+
+// WriteReportJSON writes report as an indented JSON document
+func WriteReportJSON(w io.Writer, report HTMLReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
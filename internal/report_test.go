@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewHTMLReport(t *testing.T) {
+	now := time.Now()
+	repos := []RepoReport{
+		{Name: "api", Branches: testBranches()},
+		{Name: "web", Branches: testBranches()[:1]},
+	}
+
+	report := NewHTMLReport("Fleet", repos, now)
+
+	if report.Stats.Total != 4 {
+		t.Errorf("combined Total = %d, want 4", report.Stats.Total)
+	}
+	if report.Repositories[1].Stats.Total != 1 {
+		t.Errorf("per-repo Total = %d, want 1", report.Repositories[1].Stats.Total)
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	branches := append(testBranches(), Branch{Name: "<script>alert(1)</script>", LastCommit: time.Now()})
+	report := NewHTMLReport("Branch report: demo", []RepoReport{{Name: "demo", Branches: branches}}, time.Now())
+
+	var buf bytes.Buffer
+	if err := WriteHTMLReport(&buf, report); err != nil {
+		t.Fatalf("WriteHTMLReport failed: %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"<title>Branch report: demo</title>",
+		`<span class="badge merged">merged</span>`,
+		"feature/merged",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected report to contain %q", want)
+		}
+	}
+
+	// A single repository does not get a repository column
+	if strings.Contains(html, ">Repository</th>") {
+		t.Error("unexpected repository column for single-repository report")
+	}
+	if strings.Contains(html, "ZgotmplZ") {
+		t.Error("report contains values rejected by html/template")
+	}
+}
+
+func TestWriteHTMLReport_MultipleRepositories(t *testing.T) {
+	report := NewHTMLReport("Fleet", []RepoReport{
+		{Name: "api", Branches: testBranches()},
+		{Name: "broken", Error: "not a git repository"},
+	}, time.Now())
+
+	var buf bytes.Buffer
+	if err := WriteHTMLReport(&buf, report); err != nil {
+		t.Fatalf("WriteHTMLReport failed: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), ">Repository</th>") {
+		t.Error("expected repository column for multi-repository report")
+	}
+	if !strings.Contains(buf.String(), "broken: not a git repository") {
+		t.Error("expected repository error in report")
+	}
+}
+
+func TestWriteReportJSON(t *testing.T) {
+	now := time.Now()
+	report := NewHTMLReport("Workspace <report>", []RepoReport{
+		{Name: "api", Branches: []Branch{{Name: "feature", IsMerged: true, LastCommit: now}}},
+		{Name: "web", Error: "corrupt repository"},
+	}, now)
+
+	var buf bytes.Buffer
+	if err := WriteReportJSON(&buf, report); err != nil {
+		t.Fatalf("WriteReportJSON failed: %v", err)
+	}
+
+	var decoded struct {
+		Title        string `json:"title"`
+		Repositories []struct {
+			Name  string `json:"name"`
+			Error string `json:"error"`
+		} `json:"repositories"`
+		Stats Stats `json:"stats"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.Title != "Workspace <report>" || len(decoded.Repositories) != 2 || decoded.Repositories[1].Error != "corrupt repository" {
+		t.Errorf("unexpected report: %+v", decoded)
+	}
+	if decoded.Stats.Total != 1 || decoded.Stats.ByStatus["merged"] != 1 {
+		t.Errorf("expected combined stats, got %+v", decoded.Stats)
+	}
+}
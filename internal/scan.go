@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultScanIgnore lists the directory patterns skipped by repository discovery
+var DefaultScanIgnore = []string{".*", "node_modules", "vendor"}
+
+// DiscoverRepositories returns the git repositories found under root, which
+// may itself be one. Directories are searched down to maxDepth levels below
+// root; a directory whose name or slash-separated path relative to root
+// matches one of the ignore patterns is skipped. Repositories are not
+// searched for nested repositories, and symbolic links are not followed.
+func DiscoverRepositories(root string, maxDepth int, ignore []string) ([]string, error) {
+	for _, pattern := range ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+	}
+
+	root = filepath.Clean(root)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to scan %s: not a directory", root)
+	}
+
+	var repos []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped rather than failing the scan
+			if path != root && d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		depth := 0
+		if path != root {
+			rel, _ := filepath.Rel(root, path)
+			rel = filepath.ToSlash(rel)
+			if matchesIgnore(d.Name(), rel, ignore) {
+				return filepath.SkipDir
+			}
+			depth = strings.Count(rel, "/") + 1
+		}
+
+		if isRepository(path) {
+			repos = append(repos, path)
+			return filepath.SkipDir
+		}
+		if depth >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return repos, nil
+}
+
+// isRepository reports whether dir is the root of a working tree or a bare
+// repository. .git is a directory in regular clones and a file in linked
+// worktrees and submodules.
+func isRepository(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return true
+	}
+	return isBareRepository(dir)
+}
+
+func matchesIgnore(name, rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// NameRepositories returns a report for each repository path, named by its
+// path relative to root
+func NameRepositories(root string, paths []string) []RepoReport {
+	repos := make([]RepoReport, len(paths))
+	for i, path := range paths {
+		repos[i] = RepoReport{Name: filepath.Base(path), Path: path}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." {
+			repos[i].Name = filepath.ToSlash(rel)
+		}
+	}
+	return repos
+}
+
+// AnalyzeRepositories runs analyze for each repository, with up to jobs
+// repositories in parallel, and fills in their branches and statistics.
+// A repository that fails to be analyzed gets its Error set and does not
+// affect the others.
+func AnalyzeRepositories(repos []RepoReport, jobs int, now time.Time, analyze func(repo RepoReport) ([]Branch, error)) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(jobs, 1), len(repos)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				branches, err := analyze(repos[i])
+				if err != nil {
+					repos[i].Error = err.Error()
+					continue
+				}
+				repos[i].Branches = branches
+				repos[i].Stats = ComputeStats(branches, now)
+			}
+		}()
+	}
+	for i := range repos {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// PrintRepositories writes the branches of each repository as a table under
+// a heading with the repository name, followed by a summary line
+func PrintRepositories(w io.Writer, repos []RepoReport) {
+	var branches, failed int
+	for _, repo := range repos {
+		if repo.Error != "" {
+			failed++
+			fmt.Fprintf(w, "\n%s %s\n", colorize(w, colorRed, "✗ "+repo.Name), repo.Error)
+			continue
+		}
+		branches += len(repo.Branches)
+		fmt.Fprintf(w, "\n%s (%s)", colorize(w, colorBlue, repo.Name), PluralBranches(len(repo.Branches)))
+		writeBranchTable(w, repo.Branches, terminalWidth)
+	}
+
+	fmt.Fprintf(w, "\nScanned %d repositories: %s", len(repos), PluralBranches(branches))
+	if failed > 0 {
+		fmt.Fprintf(w, ", %d failed", failed)
+	}
+	fmt.Fprintln(w)
+}
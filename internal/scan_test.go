@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// makeTree creates the given directories under root. Paths ending in /.git
+// are created as directories, paths ending in .git-file as a .git file.
+func makeTree(t *testing.T, root string, paths ...string) {
+	t.Helper()
+	for _, p := range paths {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		if strings.HasSuffix(p, ".git-file") {
+			full = filepath.Join(filepath.Dir(full), ".git")
+			if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(full, []byte("gitdir: elsewhere\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(full, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiscoverRepositories(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root,
+		"api/.git",
+		"api/vendor/lib/.git", // nested repositories are not searched
+		"team/web/.git",
+		"team/worktree/.git-file",
+		"deep/a/b/c/.git",
+		"node_modules/pkg/.git",
+		".cache/repo/.git",
+		"archive/old/.git",
+		"srv/app.git/objects",
+		"srv/app.git/refs",
+	)
+	if err := os.WriteFile(filepath.Join(root, "srv", "app.git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DiscoverRepositories(root, 3, []string{".*", "node_modules", "archive/*"})
+	if err != nil {
+		t.Fatalf("DiscoverRepositories failed: %v", err)
+	}
+
+	var rel []string
+	for _, p := range got {
+		r, _ := filepath.Rel(root, p)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	want := []string{"api", "srv/app.git", "team/web", "team/worktree"}
+	if !reflect.DeepEqual(rel, want) {
+		t.Errorf("got %v, want %v", rel, want)
+	}
+}
+
+func TestDiscoverRepositories_RootIsRepository(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, ".git", "sub/.git")
+
+	got, err := DiscoverRepositories(root, 3, DefaultScanIgnore)
+	if err != nil {
+		t.Fatalf("DiscoverRepositories failed: %v", err)
+	}
+	if len(got) != 1 || got[0] != filepath.Clean(root) {
+		t.Errorf("expected only the root repository, got %v", got)
+	}
+}
+
+func TestDiscoverRepositories_Errors(t *testing.T) {
+	if _, err := DiscoverRepositories(filepath.Join(t.TempDir(), "missing"), 3, nil); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if _, err := DiscoverRepositories(t.TempDir(), 3, []string{"["}); err == nil {
+		t.Error("expected an error for an invalid ignore pattern")
+	}
+}
+
+func TestAnalyzeRepositories(t *testing.T) {
+	root := filepath.FromSlash("/src")
+	paths := []string{filepath.Join(root, "a"), filepath.Join(root, "team", "b"), filepath.Join(root, "c")}
+
+	reports := NameRepositories(root, paths)
+	AnalyzeRepositories(reports, 2, time.Now(), func(repo RepoReport) ([]Branch, error) {
+		if filepath.Base(repo.Path) == "b" {
+			return nil, errors.New("corrupt repository")
+		}
+		return []Branch{{Name: "feature", IsMerged: true}}, nil
+	})
+
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got %d", len(reports))
+	}
+	for i, want := range []string{"a", "team/b", "c"} {
+		if reports[i].Name != want {
+			t.Errorf("report %d: name = %q, want %q", i, reports[i].Name, want)
+		}
+	}
+	if reports[1].Error != "corrupt repository" || reports[1].Branches != nil {
+		t.Errorf("expected the failing repository to carry its error, got %+v", reports[1])
+	}
+	if len(reports[2].Branches) != 1 || reports[2].Stats.ByStatus["merged"] != 1 {
+		t.Errorf("expected the other repositories to be analyzed, got %+v", reports[2])
+	}
+}
+
+func TestPrintRepositories(t *testing.T) {
+	withColor(t, false)
+
+	var buf strings.Builder
+	PrintRepositories(&buf, []RepoReport{
+		{Name: "api", Branches: []Branch{{Name: "feature", IsMerged: true, LastCommit: time.Now()}}},
+		{Name: "web", Error: "corrupt repository"},
+	})
+
+	out := buf.String()
+	for _, want := range []string{"api (1 branch)", "feature", "✗ web corrupt repository", "Scanned 2 repositories: 1 branch, 1 failed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// errIncompleteHistory is returned by merge detection when it reaches commits
+// that are missing from a shallow or partial clone
+var errIncompleteHistory = errors.New("repository history is incomplete")
+
+// isPartialClone reports whether the repository was cloned with a filter, in
+// which case objects may be missing until git fetches them on demand
+func isPartialClone(repo *git.Repository) (bool, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return false, fmt.Errorf("failed to read repository config: %w", err)
+	}
+	if cfg.Raw.Section("extensions").Option("partialclone") != "" {
+		return true, nil
+	}
+	for _, remote := range cfg.Raw.Section("remote").Subsections {
+		if remote.Option("promisor") == "true" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isShallowClone reports whether the repository history was truncated with --depth
+func isShallowClone(repo *git.Repository) (bool, error) {
+	shallow, err := repo.Storer.Shallow()
+	if err != nil {
+		return false, fmt.Errorf("failed to read shallow commits: %w", err)
+	}
+	return len(shallow) > 0, nil
+}
+
+// IsShallow reports whether the repository is a shallow clone
+func (g *GitRepo) IsShallow() bool {
+	return g.shallow
+}
+
+// IsPartial reports whether the repository is a partial (filtered) clone
+func (g *GitRepo) IsPartial() bool {
+	return g.partial
+}
+
+// defaultHistoryComplete reports whether the whole history of the default
+// branch is present. Only then does "not found in the history" mean that a
+// branch is unmerged. The walk stops at shallow boundaries, so in the shallow
+// clones where it is needed it only visits the commits that were fetched.
+func (g *GitRepo) defaultHistoryComplete() (bool, error) {
+	shallow, err := g.repo.Storer.Shallow()
+	if err != nil {
+		return false, fmt.Errorf("failed to read shallow commits: %w", err)
+	}
+	boundary := make(map[plumbing.Hash]bool, len(shallow))
+	for _, hash := range shallow {
+		boundary[hash] = true
+	}
+
+	target, err := g.repo.ResolveRevision(plumbing.Revision(g.defaultBranch))
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve default branch %s: %w", g.defaultBranch, err)
+	}
+
+	visited := make(map[plumbing.Hash]bool)
+	stack := []plumbing.Hash{*target}
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[hash] {
+			continue
+		}
+		visited[hash] = true
+		if boundary[hash] {
+			return false, nil
+		}
+
+		commit, err := g.repo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+		stack = append(stack, commit.ParentHashes...)
+	}
+	return true, nil
+}
+
+// Deepen fetches depth more commits of history into a shallow clone so that
+// merge detection has enough history to work with. It does nothing if the
+// repository is not shallow. History is fetched from the configured remote.
+// Deepening needs the git binary.
+func (g *GitRepo) Deepen(depth int) error {
+	if !g.shallow {
+		return nil
+	}
+	if _, err := g.runGit("fetch", "--quiet", fmt.Sprintf("--deepen=%d", depth), g.remote); err != nil {
+		return fmt.Errorf("failed to deepen repository history: %w", err)
+	}
+
+	// Reopen the repository so the new packfiles and shallow file are picked up
+	repo, err := g.openHandle()
+	if err != nil {
+		return fmt.Errorf("failed to reopen repository: %w", err)
+	}
+	g.repo = repo
+	g.shallow, err = isShallowClone(repo)
+	return err
+}
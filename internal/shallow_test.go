@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// makeShallow marks the root commit of master as a shallow boundary, so the
+// default branch history looks truncated without removing any objects
+func makeShallow(t *testing.T, dir string) {
+	t.Helper()
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	commits, err := repo.Log(&git.LogOptions{})
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	var root plumbing.Hash
+	_ = commits.ForEach(func(c *object.Commit) error {
+		root = c.Hash
+		return nil
+	})
+	if err := repo.Storer.SetShallow([]plumbing.Hash{root}); err != nil {
+		t.Fatalf("failed to write shallow file: %v", err)
+	}
+}
+
+func TestListBranches_Shallow(t *testing.T) {
+	for _, backend := range []Backend{BackendExec, BackendGoGit} {
+		t.Run(string(backend), func(t *testing.T) {
+			dir := setupSyntheticRepo(t, 5, 2)
+			makeShallow(t, dir)
+
+			gitRepo, err := NewGitRepo(dir)
+			if err != nil {
+				t.Fatalf("NewGitRepo failed: %v", err)
+			}
+			if err := gitRepo.SetBackend(backend); err != nil {
+				t.Skipf("backend unavailable: %v", err)
+			}
+			if !gitRepo.IsShallow() {
+				t.Fatal("expected the repository to be detected as shallow")
+			}
+
+			branches, err := gitRepo.ListBranches(30, nil)
+			if err != nil {
+				t.Fatalf("ListBranches failed: %v", err)
+			}
+
+			merged, unmerged := branches[0], branches[1]
+			if !merged.IsMerged || merged.MergeUnknown {
+				t.Errorf("%s: IsMerged = %v, MergeUnknown = %v, want merged", merged.Name, merged.IsMerged, merged.MergeUnknown)
+			}
+			if unmerged.IsMerged || !unmerged.MergeUnknown {
+				t.Errorf("%s: IsMerged = %v, MergeUnknown = %v, want unknown", unmerged.Name, unmerged.IsMerged, unmerged.MergeUnknown)
+			}
+
+			// Only the definitive result may be cached
+			cache := loadAnalysisCache(filepath.Join(dir, ".git", "branch-clean", "cache"))
+			if len(cache.Entries) != 1 {
+				t.Errorf("expected 1 cache entry, got %v", cache.Entries)
+			}
+		})
+	}
+}
+
+func TestIsPartialClone(t *testing.T) {
+	dir, repo := setupTestRepo(t)
+
+	partial, err := isPartialClone(repo)
+	if err != nil || partial {
+		t.Fatalf("isPartialClone = %v, %v, want false", partial, err)
+	}
+
+	// As written by git clone --filter=blob:none
+	config, err := os.OpenFile(filepath.Join(dir, ".git", "config"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open config: %v", err)
+	}
+	fmt.Fprint(config, "[remote \"origin\"]\n\turl = https://example.com/repo.git\n\tpromisor = true\n\tpartialclonefilter = blob:none\n")
+	config.Close()
+
+	partial, err = isPartialClone(repo)
+	if err != nil || !partial {
+		t.Errorf("isPartialClone = %v, %v, want true", partial, err)
+	}
+}
+
+func TestDeepen_ConfiguredRemote(t *testing.T) {
+	requireGit(t)
+	srcDir, _ := setupTestRepo(t)
+	for i := 0; i < 3; i++ {
+		runGitIn(t, srcDir, "-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "--quiet", "--allow-empty", "-m", fmt.Sprintf("commit %d", i))
+	}
+
+	// The clone's upstream is its only working remote; origin is unreachable
+	srcPath := filepath.ToSlash(srcDir)
+	if !strings.HasPrefix(srcPath, "/") {
+		srcPath = "/" + srcPath
+	}
+	dir := filepath.Join(t.TempDir(), "clone")
+	runGitIn(t, srcDir, "clone", "--quiet", "--depth=1", "--origin=upstream", "file://"+srcPath, dir)
+	runGitIn(t, dir, "remote", "add", "origin", filepath.Join(t.TempDir(), "missing"))
+	runGitIn(t, dir, "config", "--unset", "branch.master.remote")
+
+	gitRepo, err := NewGitRepo(dir)
+	if err != nil {
+		t.Fatalf("NewGitRepo failed: %v", err)
+	}
+	if err := gitRepo.SetRemote("upstream"); err != nil {
+		t.Fatalf("SetRemote failed: %v", err)
+	}
+	if err := gitRepo.Deepen(2); err != nil {
+		t.Fatalf("Deepen failed: %v", err)
+	}
+	if !gitRepo.IsShallow() {
+		t.Error("expected the clone to stay shallow after deepening by 2 of 4 commits")
+	}
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxOldestBranches is the number of branches listed in Stats.Oldest
+const maxOldestBranches = 10
+
+// AgeBucket counts branches whose last commit falls within an age range
+type AgeBucket struct {
+	Label   string `json:"label"`
+	MinDays int    `json:"min_days"`
+	MaxDays int    `json:"max_days,omitempty"` // exclusive, 0 means unbounded
+	Count   int    `json:"count"`
+}
+
+// AuthorStats counts the branches whose tip commit was authored by one person
+type AuthorStats struct {
+	Author string `json:"author"`
+	Total  int    `json:"total"`
+	Merged int    `json:"merged"`
+	Stale  int    `json:"stale"`
+	Active int    `json:"active"`
+	// Unknown counts recent branches whose merge status is unknown
+	Unknown int `json:"unknown,omitempty"`
+}
+
+// Stats is an aggregated health report for the branches of a repository
+type Stats struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Total       int            `json:"total"`
+	ByStatus    map[string]int `json:"by_status"`
+	Protected   int            `json:"protected"`
+	Reclaimable int            `json:"reclaimable"`
+	AgeBuckets  []AgeBucket    `json:"age_buckets"`
+	Authors     []AuthorStats  `json:"authors"`
+	Oldest      []Branch       `json:"oldest"`
+}
+
+func newAgeBuckets() []AgeBucket {
+	return []AgeBucket{
+		{Label: "< 1 week", MinDays: 0, MaxDays: 7},
+		{Label: "1-4 weeks", MinDays: 7, MaxDays: 30},
+		{Label: "1-3 months", MinDays: 30, MaxDays: 90},
+		{Label: "3-6 months", MinDays: 90, MaxDays: 180},
+		{Label: "6-12 months", MinDays: 180, MaxDays: 365},
+		{Label: "> 1 year", MinDays: 365},
+	}
+}
+
+// ComputeStats aggregates branches into counts by status, age and author.
+// Reclaimable counts the branches a default cleanup would offer for deletion.
+// Branches that could not be analyzed are only counted by status, as their
+// age and author are unknown.
+func ComputeStats(branches []Branch, now time.Time) Stats {
+	stats := Stats{
+		GeneratedAt: now,
+		Total:       len(branches),
+		ByStatus:    map[string]int{"merged": 0, "stale": 0, "active": 0},
+		AgeBuckets:  newAgeBuckets(),
+		Authors:     []AuthorStats{},
+		Reclaimable: len(FilterBranches(branches, false, false)),
+	}
+
+	authors := make(map[string]*AuthorStats)
+	for _, b := range branches {
+		status := b.Status()
+		stats.ByStatus[status]++
+		if b.Protected {
+			stats.Protected++
+		}
+		if b.Error != "" {
+			continue
+		}
+
+		days := int(now.Sub(b.LastCommit).Hours() / 24)
+		for i := range stats.AgeBuckets {
+			bucket := &stats.AgeBuckets[i]
+			if days >= bucket.MinDays && (bucket.MaxDays == 0 || days < bucket.MaxDays) {
+				bucket.Count++
+				break
+			}
+		}
+
+		name := b.Author
+		if name == "" {
+			name = "(unknown)"
+		}
+		author, ok := authors[name]
+		if !ok {
+			author = &AuthorStats{Author: name}
+			authors[name] = author
+		}
+		author.Total++
+		switch status {
+		case "merged":
+			author.Merged++
+		case "stale":
+			author.Stale++
+		case "unknown":
+			author.Unknown++
+		default:
+			author.Active++
+		}
+	}
+
+	for _, author := range authors {
+		stats.Authors = append(stats.Authors, *author)
+	}
+	sort.Slice(stats.Authors, func(i, j int) bool {
+		if stats.Authors[i].Total != stats.Authors[j].Total {
+			return stats.Authors[i].Total > stats.Authors[j].Total
+		}
+		return stats.Authors[i].Author < stats.Authors[j].Author
+	})
+
+	stats.Oldest = []Branch{}
+	for _, b := range branches {
+		if b.Error == "" {
+			stats.Oldest = append(stats.Oldest, b)
+		}
+	}
+	sort.SliceStable(stats.Oldest, func(i, j int) bool {
+		return stats.Oldest[i].LastCommit.Before(stats.Oldest[j].LastCommit)
+	})
+	if len(stats.Oldest) > maxOldestBranches {
+		stats.Oldest = stats.Oldest[:maxOldestBranches]
+	}
+
+	return stats
+}
+
+// WriteStatsJSON writes stats as an indented JSON document
+func WriteStatsJSON(w io.Writer, stats Stats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(stats); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
+
+// PrintStats writes stats as a human-readable report
+func PrintStats(w io.Writer, stats Stats) {
+	fmt.Fprintf(w, "\nBranches:     %d\n", stats.Total)
+	fmt.Fprintf(w, "  %s %d\n", colorize(w, colorGreen, padRight("merged", statusWidth)), stats.ByStatus["merged"])
+	fmt.Fprintf(w, "  %s %d\n", colorize(w, colorYellow, padRight("stale", statusWidth)), stats.ByStatus["stale"])
+	fmt.Fprintf(w, "  %s %d\n", colorize(w, colorBlue, padRight("active", statusWidth)), stats.ByStatus["active"])
+	if unknown := stats.ByStatus["unknown"]; unknown > 0 {
+		fmt.Fprintf(w, "  %s %d\n", colorize(w, colorRed, padRight("unknown", statusWidth)), unknown)
+	}
+	fmt.Fprintf(w, "Protected:    %d\n", stats.Protected)
+	fmt.Fprintf(w, "Reclaimable:  %d\n", stats.Reclaimable)
+
+	fmt.Fprintln(w, "\nAge")
+	fmt.Fprintln(w, strings.Repeat("-", 40))
+	maxCount := 0
+	for _, bucket := range stats.AgeBuckets {
+		maxCount = max(maxCount, bucket.Count)
+	}
+	for _, bucket := range stats.AgeBuckets {
+		bar := ""
+		if maxCount > 0 {
+			bar = strings.Repeat("█", bucket.Count*20/maxCount)
+		}
+		line := fmt.Sprintf("%s %5d %s", padRight(bucket.Label, 12), bucket.Count, bar)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+
+	if len(stats.Authors) > 0 {
+		authorWidth := utf8.RuneCountInString("Author")
+		for _, a := range stats.Authors {
+			authorWidth = max(authorWidth, utf8.RuneCountInString(a.Author))
+		}
+		fmt.Fprintf(w, "\n%s %6s %6s %6s %6s %7s\n", padRight("Author", authorWidth), "Total", "Merged", "Stale", "Active", "Unknown")
+		fmt.Fprintln(w, strings.Repeat("-", authorWidth+36))
+		for _, a := range stats.Authors {
+			fmt.Fprintf(w, "%s %6d %6d %6d %6d %7d\n", padRight(a.Author, authorWidth), a.Total, a.Merged, a.Stale, a.Active, a.Unknown)
+		}
+	}
+
+	if len(stats.Oldest) > 0 {
+		fmt.Fprint(w, "\nOldest branches")
+		writeBranchTable(w, stats.Oldest, terminalWidth)
+	}
+}
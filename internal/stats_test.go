@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+
+	branches := []Branch{
+		{Name: "a", IsMerged: true, LastCommit: daysAgo(2), Author: "Alice"},
+		{Name: "b", IsStale: true, LastCommit: daysAgo(45), Author: "Bob"},
+		{Name: "c", IsMerged: true, IsStale: true, LastCommit: daysAgo(400), Author: "Alice"},
+		{Name: "d", LastCommit: daysAgo(10), Author: "Alice"},
+		{Name: "release/1.0", IsMerged: true, Protected: true, LastCommit: daysAgo(200)},
+	}
+
+	stats := ComputeStats(branches, now)
+
+	if stats.Total != 5 {
+		t.Errorf("Total = %d, want 5", stats.Total)
+	}
+	if stats.ByStatus["merged"] != 3 || stats.ByStatus["stale"] != 1 || stats.ByStatus["active"] != 1 {
+		t.Errorf("unexpected status counts: %v", stats.ByStatus)
+	}
+	if stats.Protected != 1 {
+		t.Errorf("Protected = %d, want 1", stats.Protected)
+	}
+	if stats.Reclaimable != 3 {
+		t.Errorf("Reclaimable = %d, want 3", stats.Reclaimable)
+	}
+
+	wantBuckets := []int{1, 1, 1, 0, 1, 1}
+	for i, bucket := range stats.AgeBuckets {
+		if bucket.Count != wantBuckets[i] {
+			t.Errorf("bucket %q = %d, want %d", bucket.Label, bucket.Count, wantBuckets[i])
+		}
+	}
+
+	if len(stats.Authors) != 3 || stats.Authors[0].Author != "Alice" || stats.Authors[0].Total != 3 {
+		t.Errorf("unexpected authors: %+v", stats.Authors)
+	}
+	if stats.Authors[0].Merged != 2 || stats.Authors[0].Active != 1 {
+		t.Errorf("unexpected counts for Alice: %+v", stats.Authors[0])
+	}
+
+	if stats.Oldest[0].Name != "c" || stats.Oldest[1].Name != "release/1.0" {
+		t.Errorf("unexpected oldest order: %s, %s", stats.Oldest[0].Name, stats.Oldest[1].Name)
+	}
+}
+
+func TestComputeStats_OldestLimit(t *testing.T) {
+	now := time.Now()
+	var branches []Branch
+	for i := 0; i < 25; i++ {
+		branches = append(branches, Branch{Name: fmt.Sprintf("b%d", i), LastCommit: now.AddDate(0, 0, -i)})
+	}
+
+	stats := ComputeStats(branches, now)
+	if len(stats.Oldest) != maxOldestBranches {
+		t.Errorf("expected %d oldest branches, got %d", maxOldestBranches, len(stats.Oldest))
+	}
+	if stats.Oldest[0].Name != "b24" {
+		t.Errorf("expected b24 to be the oldest, got %s", stats.Oldest[0].Name)
+	}
+}
+
+func TestComputeStats_Unknown(t *testing.T) {
+	now := time.Now()
+	branches := []Branch{
+		{Name: "ok", Author: "alice", LastCommit: now},
+		{Name: "broken", Error: "object not found"},
+	}
+
+	stats := ComputeStats(branches, now)
+	if stats.Total != 2 || stats.ByStatus["unknown"] != 1 {
+		t.Errorf("expected 1 unknown of 2 branches, got %d of %d", stats.ByStatus["unknown"], stats.Total)
+	}
+	if len(stats.Authors) != 1 || len(stats.Oldest) != 1 || stats.Oldest[0].Name != "ok" {
+		t.Errorf("expected broken branch to be left out of authors and oldest, got %+v %+v", stats.Authors, stats.Oldest)
+	}
+}
+
+func TestComputeStats_Empty(t *testing.T) {
+	stats := ComputeStats(nil, time.Now())
+
+	var buf bytes.Buffer
+	if err := WriteStatsJSON(&buf, stats); err != nil {
+		t.Fatalf("WriteStatsJSON failed: %v", err)
+	}
+
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if string(decoded["authors"]) != "[]" || string(decoded["oldest"]) != "[]" {
+		t.Errorf("expected empty arrays, got authors=%s oldest=%s", decoded["authors"], decoded["oldest"])
+	}
+}
+
+func TestPrintStats(t *testing.T) {
+	withColor(t, false)
+
+	branches := []Branch{
+		{Name: "a", IsMerged: true, LastCommit: time.Now(), Author: "Alice"},
+		{Name: "b", MergeUnknown: true, LastCommit: time.Now(), Author: "Alice"},
+	}
+
+	var buf bytes.Buffer
+	PrintStats(&buf, ComputeStats(branches, time.Now()))
+
+	// The author columns add up to the total
+	for _, want := range []string{"Branches:     2", "Reclaimable:  1", "Unknown", "Alice       2      1      0      0       1", "Oldest branches"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected output to contain %q\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Summary aggregates a set of branches. It is the data passed to the
+// optional "header" and "footer" blocks of an output template.
+type Summary struct {
+	Total       int       `json:"total"`
+	Merged      int       `json:"merged"`
+	Stale       int       `json:"stale"`
+	Active      int       `json:"active"`
+	Unknown     int       `json:"unknown"`
+	Protected   int       `json:"protected"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Branches    []Branch  `json:"branches"`
+}
+
+// Summarize counts branches by status
+func Summarize(branches []Branch) Summary {
+	summary := Summary{
+		Total:       len(branches),
+		GeneratedAt: time.Now(),
+		Branches:    branches,
+	}
+	for _, b := range branches {
+		switch b.Status() {
+		case "merged":
+			summary.Merged++
+		case "stale":
+			summary.Stale++
+		case "unknown":
+			summary.Unknown++
+		default:
+			summary.Active++
+		}
+		if b.Protected {
+			summary.Protected++
+		}
+	}
+	return summary
+}
+
+var templateColors = map[string]string{
+	"red":    colorRed,
+	"green":  colorGreen,
+	"yellow": colorYellow,
+	"blue":   colorBlue,
+	"gray":   colorGray,
+}
+
+// templateFuncs are the helper functions available to output templates
+var templateFuncs = template.FuncMap{
+	"age": getAgeString,
+	"days": func(t time.Time) int {
+		return int(time.Since(t).Hours() / 24)
+	},
+	"color": colorFunc(os.Stdout),
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	},
+	"pad":   func(width int, s string) string { return padRight(s, width) },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+}
+
+// colorFunc returns the template "color" function for output written to w
+func colorFunc(w io.Writer) func(name, s string) (string, error) {
+	return func(name, s string) (string, error) {
+		color, ok := templateColors[name]
+		if !ok {
+			return "", fmt.Errorf("unknown color %q", name)
+		}
+		return colorize(w, color, s), nil
+	}
+}
+
+// templateFormatter executes a user-defined template once per branch
+type templateFormatter struct {
+	tmpl *template.Template
+}
+
+// NewTemplateFormatter parses text as a Go template that is executed once per
+// branch, each result on its own line. If the template defines "header" or
+// "footer" blocks they are executed with the Summary before and after the rows.
+func NewTemplateFormatter(text string) (Formatter, error) {
+	tmpl, err := template.New("branch").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return templateFormatter{tmpl: tmpl}, nil
+}
+
+func (f templateFormatter) Format(w io.Writer, branches []Branch) error {
+	summary := Summarize(branches)
+
+	tmpl, err := f.tmpl.Clone()
+	if err != nil {
+		return fmt.Errorf("failed to prepare template: %w", err)
+	}
+	tmpl.Funcs(template.FuncMap{"color": colorFunc(w)})
+
+	if header := tmpl.Lookup("header"); header != nil {
+		if err := header.Execute(w, summary); err != nil {
+			return fmt.Errorf("failed to execute header template: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, b := range branches {
+		buf.Reset()
+		if err := tmpl.Execute(&buf, b); err != nil {
+			return fmt.Errorf("failed to execute template for %s: %w", b.Name, err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if footer := tmpl.Lookup("footer"); footer != nil {
+		if err := footer.Execute(w, summary); err != nil {
+			return fmt.Errorf("failed to execute footer template: %w", err)
+		}
+	}
+	return nil
+}
+
+// UnescapeTemplate expands \t, \n and \\ in the literal text of a template given
+// on the command line. Text inside {{ }} actions is left untouched so string
+// literals in actions keep Go's own escaping rules.
+func UnescapeTemplate(text string) string {
+	replacer := strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`)
+
+	var sb strings.Builder
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			sb.WriteString(replacer.Replace(text))
+			return sb.String()
+		}
+		end := strings.Index(text[start:], "}}")
+		if end < 0 {
+			sb.WriteString(replacer.Replace(text[:start]))
+			sb.WriteString(text[start:])
+			return sb.String()
+		}
+		end += start + 2
+		sb.WriteString(replacer.Replace(text[:start]))
+		sb.WriteString(text[start:end])
+		text = text[end:]
+	}
+}
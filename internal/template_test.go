@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	f, err := NewTemplateFormatter("{{.Name}}\t{{.LastCommit.Format \"2006-01-02\"}}")
+	if err != nil {
+		t.Fatalf("NewTemplateFormatter failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, testBranches()); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected one line per branch, got %d", len(lines))
+	}
+	if lines[0] != "feature/merged\t2026-01-24" {
+		t.Errorf("unexpected first line: %q", lines[0])
+	}
+}
+
+func TestTemplateFormatter_HeaderFooter(t *testing.T) {
+	text := `{{define "header"}}total={{.Total}} merged={{.Merged}}
+{{end}}{{define "footer"}}end
+{{end}}{{.Name | upper}}`
+
+	f, err := NewTemplateFormatter(text)
+	if err != nil {
+		t.Fatalf("NewTemplateFormatter failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, testBranches()); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	want := "total=3 merged=1\nFEATURE/MERGED\nBUGFIX/STALE\nFEATURE/PIPE|NAME\nend\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTemplateFormatter_Funcs(t *testing.T) {
+	withColor(t, false)
+
+	f, err := NewTemplateFormatter(`{{json .Name}} {{color "green" .Status}} {{age .LastCommit}}`)
+	if err != nil {
+		t.Fatalf("NewTemplateFormatter failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, testBranches()[:1]); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), `"feature/merged" merged `) {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestTemplateFormatter_Errors(t *testing.T) {
+	if _, err := NewTemplateFormatter("{{.Name"); err == nil {
+		t.Error("expected parse error")
+	}
+
+	f, err := NewTemplateFormatter(`{{color "purple" .Name}}`)
+	if err != nil {
+		t.Fatalf("NewTemplateFormatter failed: %v", err)
+	}
+	if err := f.Format(&bytes.Buffer{}, testBranches()); err == nil {
+		t.Error("expected error for unknown color")
+	}
+}
+
+func TestUnescapeTemplate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{{.Name}}\t{{.IsMerged}}`, "{{.Name}}\t{{.IsMerged}}"},
+		{`a\nb`, "a\nb"},
+		{`{{printf "%s\n" .Name}}`, `{{printf "%s\n" .Name}}`},
+		{`x\\t`, `x\t`},
+	}
+
+	for _, tt := range tests {
+		if got := UnescapeTemplate(tt.in); got != tt.want {
+			t.Errorf("UnescapeTemplate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize(testBranches())
+	if summary.Total != 3 || summary.Merged != 1 || summary.Stale != 1 || summary.Active != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
@@ -3,10 +3,14 @@ package internal
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/manifoldco/promptui"
+	"golang.org/x/term"
 )
 
 var (
@@ -23,53 +27,175 @@ const (
 	colorGray   = "\033[90m"
 )
 
-func PrintBranches(branches []Branch, mergedOnly, staleOnly bool) {
-	fmt.Printf("\n%-30s %-10s %-10s %s\n", "Branch", "Status", "Age", "Last Commit")
-	fmt.Println(strings.Repeat("-", 80))
+// ColorMode controls when ANSI color codes are written to the output
+type ColorMode string
 
-	for _, b := range branches {
-		if mergedOnly && !b.IsMerged {
-			continue
-		}
-		if staleOnly && !b.IsStale {
-			continue
-		}
+const (
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+// Table column widths, measured in visible characters
+const (
+	statusWidth   = 8
+	ageWidth      = 12
+	dateWidth     = 11
+	minNameWidth  = 12
+	columnPadding = 3
+)
 
-		status := getStatusString(b)
-		age := getAgeString(b.LastCommit)
-		date := b.LastCommit.Format("2006-01-02")
+var (
+	// colorEnabled reports whether color codes may be emitted at all
+	colorEnabled = true
+	// colorAuto limits colors to writers that are terminals
+	colorAuto = false
+	// terminalWidth is the width used to truncate table rows, 0 means unlimited
+	terminalWidth = 0
+)
 
-		if b.Protected {
-			fmt.Printf("%s%-30s%s %s %s %s\n", colorGray, b.Name, colorReset, status, age, date)
-		} else {
-			fmt.Printf("%-30s %s %s %s\n", b.Name, status, age, date)
+// ConfigureOutput applies the color policy and detects the terminal width for out.
+// In auto mode colors are only written to streams that are terminals, and only
+// when NO_COLOR is unset.
+func ConfigureOutput(mode ColorMode, out *os.File) error {
+	tty := isTerminal(out)
+
+	switch mode {
+	case ColorAlways:
+		colorEnabled, colorAuto = true, false
+	case ColorNever:
+		colorEnabled, colorAuto = false, false
+	case ColorAuto, "":
+		colorEnabled, colorAuto = os.Getenv("NO_COLOR") == "", true
+	default:
+		return fmt.Errorf("invalid color mode: %s (must be 'auto', 'always' or 'never')", mode)
+	}
+
+	terminalWidth = 0
+	if tty {
+		if width, _, err := term.GetSize(int(out.Fd())); err == nil && width > 0 {
+			terminalWidth = width
 		}
 	}
+	return nil
+}
+
+// Interactive reports whether prompts can be shown, that is whether standard
+// input is a terminal
+func Interactive() bool {
+	return isTerminal(os.Stdin)
+}
+
+func isTerminal(f *os.File) bool {
+	return f != nil && term.IsTerminal(int(f.Fd()))
+}
+
+// useColor reports whether color codes should be written to w
+func useColor(w io.Writer) bool {
+	if !colorEnabled {
+		return false
+	}
+	if !colorAuto {
+		return true
+	}
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// colorize wraps s in the given color when colors are enabled for w
+func colorize(w io.Writer, color, s string) string {
+	if !useColor(w) {
+		return s
+	}
+	return color + s + colorReset
 }
 
-func getStatusString(b Branch) string {
-	if b.IsMerged {
-		return colorGreen + "merged" + colorReset + "   "
+// padRight pads s with spaces to width visible characters
+func padRight(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// truncate shortens s to at most width visible characters, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// writeBranchTable writes branches as an aligned table. Column widths are computed
+// from the visible text so color codes never shift the columns. When width is
+// positive, long branch names are truncated so each row fits on one line.
+func writeBranchTable(w io.Writer, branches []Branch, width int) {
+	nameWidth := utf8.RuneCountInString("Branch")
+	for _, b := range branches {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(b.Name))
+	}
+	if width > 0 {
+		available := width - statusWidth - ageWidth - dateWidth - columnPadding
+		nameWidth = min(nameWidth, max(available, minNameWidth))
 	}
-	if b.IsStale {
-		return colorYellow + "stale" + colorReset + "    "
+
+	fmt.Fprintf(w, "\n%s %s %s %s\n",
+		padRight("Branch", nameWidth), padRight("Status", statusWidth), padRight("Age", ageWidth), "Last Commit")
+	fmt.Fprintln(w, strings.Repeat("-", nameWidth+statusWidth+ageWidth+dateWidth+columnPadding))
+
+	for _, b := range branches {
+		name := padRight(truncate(b.Name, nameWidth), nameWidth)
+		if b.Protected {
+			name = colorize(w, colorGray, name)
+		}
+
+		status := getStatusString(w, b)
+		age := padRight(getAgeString(b.LastCommit), ageWidth)
+		date := getDateString(b.LastCommit)
+
+		fmt.Fprintf(w, "%s %s %s %s\n", name, status, age, date)
 	}
-	return colorBlue + "active" + colorReset + "   "
+}
+
+// statusColors maps each branch status to its table color
+var statusColors = map[string]string{
+	"merged":  colorGreen,
+	"stale":   colorYellow,
+	"active":  colorBlue,
+	"unknown": colorRed,
+}
+
+func getStatusString(w io.Writer, b Branch) string {
+	status := b.Status()
+	return colorize(w, statusColors[status], padRight(status, statusWidth))
 }
 
 func getAgeString(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
 	days := int(time.Since(t).Hours() / 24)
 	if days == 0 {
-		return "today     "
+		return "today"
 	}
 	if days == 1 {
-		return "1 day ago "
+		return "1 day ago"
 	}
 	return fmt.Sprintf("%d days ago", days)
 }
 
+// getDateString formats the day of t, or "-" when t is unknown
+func getDateString(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
 // FilterBranches filters branches based on merge and stale status.
-// Protected branches are always excluded.
+// Protected branches and branches that could not be analyzed are always excluded.
 //
 // Filtering logic:
 // - If mergedOnly is true: only include merged branches
@@ -79,8 +205,8 @@ func getAgeString(t time.Time) string {
 func FilterBranches(branches []Branch, mergedOnly, staleOnly bool) []Branch {
 	var filtered []Branch
 	for _, b := range branches {
-		// Always skip protected branches
-		if b.Protected {
+		// Always skip protected branches and branches with unknown status
+		if b.Protected || b.Error != "" {
 			continue
 		}
 
@@ -110,7 +236,42 @@ func FilterBranches(branches []Branch, mergedOnly, staleOnly bool) []Branch {
 	return filtered
 }
 
-func SelectBranches(branches []Branch) ([]Branch, error) {
+// PrintBranchWarnings writes a warning summary of the branches that could not
+// be analyzed and of those whose merge status is unknown. It writes nothing if
+// all branches were fully analyzed.
+func PrintBranchWarnings(w io.Writer, branches []Branch) {
+	var failed []Branch
+	unknown := 0
+	for _, b := range branches {
+		if b.Error != "" {
+			failed = append(failed, b)
+		} else if b.MergeUnknown {
+			unknown++
+		}
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintln(w, colorize(w, colorYellow, fmt.Sprintf("⚠ %s could not be analyzed and will not be cleaned up:", PluralBranches(len(failed)))))
+		for _, b := range failed {
+			fmt.Fprintf(w, "  %s: %s\n", b.Name, b.Error)
+		}
+	}
+	if unknown > 0 {
+		fmt.Fprintln(w, colorize(w, colorYellow, fmt.Sprintf("⚠ %s may have been merged, but the repository history is incomplete (shallow or partial clone); use --deepen to fetch more history", PluralBranches(unknown))))
+	}
+}
+
+// PluralBranches returns "1 branch" or "n branches"
+func PluralBranches(n int) string {
+	if n == 1 {
+		return "1 branch"
+	}
+	return fmt.Sprintf("%d branches", n)
+}
+
+// SelectBranches lets the user pick branches from a list. The prompt is
+// written to out.
+func SelectBranches(out io.Writer, branches []Branch) ([]Branch, error) {
 	if len(branches) == 0 {
 		return nil, nil
 	}
@@ -134,12 +295,13 @@ func SelectBranches(branches []Branch) ([]Branch, error) {
 
 			items[i] = fmt.Sprintf("%s %s %s", checkbox, b.Name, status)
 		}
-		items[len(branches)] = colorGreen + "✓ Confirm selection" + colorReset
+		items[len(branches)] = colorize(out, colorGreen, "✓ Confirm selection")
 
 		prompt := promptui.Select{
-			Label: "Select branches to delete (↑/↓ to navigate, enter to toggle/confirm)",
-			Items: items,
-			Size:  15,
+			Label:  "Select branches to delete (↑/↓ to navigate, enter to toggle/confirm)",
+			Items:  items,
+			Size:   15,
+			Stdout: promptOutput(out),
 			Templates: &promptui.SelectTemplates{
 				Active:   "→ {{ . | cyan }}",
 				Inactive: "  {{ . }}",
@@ -175,22 +337,43 @@ func SelectBranches(branches []Branch) ([]Branch, error) {
 	return selected, nil
 }
 
-func ConfirmDeletion(branches []Branch, dryRun bool) bool {
-	action := "delete"
+// ConfirmAction asks before applying action (delete, archive, purge) to the
+// named branches. The list and the prompt are written to out.
+func ConfirmAction(out io.Writer, action string, names []string, dryRun bool) bool {
 	if dryRun {
-		action = "would delete"
+		action = "would " + action
 	}
 
-	fmt.Printf("\n%sYou are about to %s %d branch(es):%s\n", colorRed, action, len(branches), colorReset)
-	for _, b := range branches {
-		fmt.Printf("  - %s\n", b.Name)
+	fmt.Fprintf(out, "\n%s\n", colorize(out, colorRed, fmt.Sprintf("You are about to %s %d branch(es):", action, len(names))))
+	for _, name := range names {
+		fmt.Fprintf(out, "  - %s\n", name)
 	}
 
 	prompt := promptui.Prompt{
 		Label:     "Continue",
 		IsConfirm: true,
+		Stdout:    promptOutput(out),
 	}
 
 	_, err := prompt.Run()
 	return err == nil
 }
+
+// promptOutput returns the writer for a promptui prompt written to out. nil
+// keeps promptui's default, standard output.
+func promptOutput(out io.Writer) io.WriteCloser {
+	if out == os.Stdout {
+		return nil
+	}
+	if wc, ok := out.(io.WriteCloser); ok {
+		return wc
+	}
+	return nopWriteCloser{out}
+}
+
+// nopWriteCloser is an io.WriteCloser whose Close does nothing
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -1,9 +1,13 @@
 package internal
 
 import (
+	"bytes"
+	"io"
+	"os"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
 func TestGetStatusString(t *testing.T) {
@@ -19,7 +23,7 @@ func TestGetStatusString(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getStatusString(tt.branch)
+			got := getStatusString(io.Discard, tt.branch)
 			if !strings.Contains(got, tt.want) {
 				t.Errorf("getStatusString() = %v, want to contain %v", got, tt.want)
 			}
@@ -142,8 +146,39 @@ func TestFilterBranches_AllProtected(t *testing.T) {
 	}
 }
 
+func TestFilterBranches_SkipsUnknown(t *testing.T) {
+	branches := []Branch{
+		{Name: "broken", IsMerged: true, Error: "object not found"},
+		{Name: "merged", IsMerged: true},
+	}
+
+	filtered := FilterBranches(branches, true, false)
+	if len(filtered) != 1 || filtered[0].Name != "merged" {
+		t.Errorf("expected only the analyzed branch, got %+v", filtered)
+	}
+}
+
+func TestPrintBranchWarnings(t *testing.T) {
+	withColor(t, false)
+
+	var buf bytes.Buffer
+	PrintBranchWarnings(&buf, []Branch{{Name: "ok"}})
+	if buf.Len() != 0 {
+		t.Errorf("expected no output without broken branches, got %q", buf.String())
+	}
+
+	PrintBranchWarnings(&buf, []Branch{
+		{Name: "ok"},
+		{Name: "broken", Error: "failed to read tip commit: object not found"},
+	})
+	want := "⚠ 1 branch could not be analyzed and will not be cleaned up:\n  broken: failed to read tip commit: object not found\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
 func TestSelectBranches_EmptyList(t *testing.T) {
-	selected, err := SelectBranches([]Branch{})
+	selected, err := SelectBranches(io.Discard, []Branch{})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -151,3 +186,128 @@ func TestSelectBranches_EmptyList(t *testing.T) {
 		t.Error("expected nil result for empty input")
 	}
 }
+
+func withColor(t *testing.T, enabled bool) {
+	t.Helper()
+	prev := colorEnabled
+	colorEnabled = enabled
+	t.Cleanup(func() { colorEnabled = prev })
+}
+
+func TestGetStatusString_NoColor(t *testing.T) {
+	withColor(t, false)
+
+	got := getStatusString(io.Discard, Branch{IsMerged: true})
+	if strings.Contains(got, "\033[") {
+		t.Errorf("expected no escape codes, got %q", got)
+	}
+	if len(got) != statusWidth {
+		t.Errorf("expected status padded to %d, got %d", statusWidth, len(got))
+	}
+}
+
+func TestWriteBranchTable_Alignment(t *testing.T) {
+	branches := []Branch{
+		{Name: "feature/a", IsMerged: true, LastCommit: time.Now()},
+		{Name: "feature/much-longer-name", IsStale: true, LastCommit: time.Now()},
+		{Name: "main", Protected: true, LastCommit: time.Now()},
+	}
+
+	for _, enabled := range []bool{false, true} {
+		withColor(t, enabled)
+
+		var buf bytes.Buffer
+		writeBranchTable(&buf, branches, 0)
+
+		// Every row must start its last column where the header's "Last Commit" starts
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		want := strings.Index(lines[0], "Last Commit")
+		for i, line := range lines[2:] {
+			plain := stripANSI(line)
+			got := utf8.RuneCountInString(plain) - len("2006-01-02")
+			if got != want {
+				t.Errorf("color=%v: row %d date column at %d, header at %d", enabled, i, got, want)
+			}
+		}
+	}
+}
+
+func TestWriteBranchTable_Truncates(t *testing.T) {
+	withColor(t, false)
+
+	branches := []Branch{
+		{Name: "feature/" + strings.Repeat("x", 100), LastCommit: time.Now()},
+	}
+
+	var buf bytes.Buffer
+	writeBranchTable(&buf, branches, 60)
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if n := utf8.RuneCountInString(line); n > 60 {
+			t.Errorf("line exceeds terminal width (%d > 60): %q", n, line)
+		}
+	}
+	if !strings.Contains(buf.String(), "…") {
+		t.Error("expected truncated name to end with an ellipsis")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly-10", 10, "exactly-10"},
+		{"longer-than-ten", 10, "longer-th…"},
+		{"unlimited", 0, "unlimited"},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.in, tt.width); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestConfigureOutput(t *testing.T) {
+	prevEnabled, prevAuto := colorEnabled, colorAuto
+	t.Cleanup(func() { colorEnabled, colorAuto = prevEnabled, prevAuto })
+	t.Setenv("NO_COLOR", "")
+
+	// Test output is not a terminal, so auto disables colors for every stream
+	if err := ConfigureOutput(ColorAuto, os.Stdout); err != nil {
+		t.Fatalf("ConfigureOutput failed: %v", err)
+	}
+	for _, w := range []io.Writer{os.Stdout, os.Stderr, &bytes.Buffer{}} {
+		if useColor(w) {
+			t.Errorf("expected colors disabled for non-terminal output %T", w)
+		}
+	}
+
+	if err := ConfigureOutput(ColorAlways, os.Stdout); err != nil {
+		t.Fatalf("ConfigureOutput failed: %v", err)
+	}
+	if !useColor(os.Stderr) || !useColor(&bytes.Buffer{}) {
+		t.Error("expected colors enabled with --color always")
+	}
+
+	if err := ConfigureOutput("sometimes", os.Stdout); err == nil {
+		t.Error("expected error for invalid color mode")
+	}
+}
+
+func stripANSI(s string) string {
+	for {
+		start := strings.Index(s, "\033[")
+		if start < 0 {
+			return s
+		}
+		end := strings.IndexByte(s[start:], 'm')
+		if end < 0 {
+			return s
+		}
+		s = s[:start] + s[start+end+1:]
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorkspaceFile is the default name of the workspace manifest
+const WorkspaceFile = "branch-clean.workspace.yaml"
+
+// WorkspaceRepo is a repository listed in a workspace manifest. Its
+// configuration overrides the workspace defaults and the repository's own
+// configuration file.
+type WorkspaceRepo struct {
+	Path   string `yaml:"path"`
+	Name   string `yaml:"name,omitempty"`
+	Config `yaml:",inline"`
+}
+
+// Workspace is a manifest of repositories cleaned up together. The
+// top-level configuration applies to every repository.
+type Workspace struct {
+	Config       `yaml:",inline"`
+	Repositories []WorkspaceRepo `yaml:"repositories"`
+}
+
+// LoadWorkspace reads the workspace manifest at path. Relative repository and
+// audit log paths are resolved against the directory of the manifest, and
+// repositories without a name are named after their path as written in the
+// manifest.
+func LoadWorkspace(path string) (*Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace manifest: %w", err)
+	}
+
+	var workspace Workspace
+	if err := yaml.Unmarshal(data, &workspace); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace manifest %s: %w", path, err)
+	}
+	if len(workspace.Repositories) == 0 {
+		return nil, fmt.Errorf("workspace manifest %s lists no repositories", path)
+	}
+
+	dir := filepath.Dir(path)
+	workspace.AuditLog = resolvePath(workspace.AuditLog, dir)
+	names := make(map[string]bool)
+	for i := range workspace.Repositories {
+		repo := &workspace.Repositories[i]
+		if repo.Path == "" {
+			return nil, fmt.Errorf("workspace manifest %s: repository %d has no path", path, i+1)
+		}
+		if repo.Name == "" {
+			repo.Name = repo.Path
+		}
+		if names[repo.Name] {
+			return nil, fmt.Errorf("workspace manifest %s: duplicate repository %s", path, repo.Name)
+		}
+		names[repo.Name] = true
+
+		repo.Path = resolvePath(repo.Path, dir)
+		repo.AuditLog = resolvePath(repo.AuditLog, dir)
+	}
+	return &workspace, nil
+}
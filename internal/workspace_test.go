@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), WorkspaceFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	return path
+}
+
+func TestLoadWorkspace(t *testing.T) {
+	path := writeManifest(t, `
+stale_days: 45
+protected: [main]
+audit_log: logs/audit.log
+repositories:
+  - path: services/api
+    audit_log: api.log
+    default_branch: trunk
+    remote: upstream
+  - path: /srv/git/web
+    name: web
+    stale_days: 90
+    protected: [main, "release/*"]
+`)
+
+	workspace, err := LoadWorkspace(path)
+	if err != nil {
+		t.Fatalf("LoadWorkspace failed: %v", err)
+	}
+	if workspace.StaleDays != 45 || len(workspace.Protected) != 1 {
+		t.Errorf("unexpected workspace defaults: %+v", workspace.Config)
+	}
+	if want := filepath.Join(filepath.Dir(path), "logs", "audit.log"); workspace.AuditLog != want {
+		t.Errorf("expected audit_log to be resolved against the manifest, got %s, want %s", workspace.AuditLog, want)
+	}
+	if len(workspace.Repositories) != 2 {
+		t.Fatalf("expected 2 repositories, got %d", len(workspace.Repositories))
+	}
+
+	api := workspace.Repositories[0]
+	if api.Name != "services/api" || api.Path != filepath.Join(filepath.Dir(path), "services", "api") {
+		t.Errorf("expected the path to be resolved against the manifest, got %+v", api)
+	}
+	if api.AuditLog != filepath.Join(filepath.Dir(path), "api.log") {
+		t.Errorf("expected the audit log to be resolved against the manifest, got %s", api.AuditLog)
+	}
+	if api.DefaultBranch != "trunk" || api.Remote != "upstream" {
+		t.Errorf("unexpected overrides: %+v", api.Config)
+	}
+
+	web := workspace.Repositories[1]
+	if web.Name != "web" || web.StaleDays != 90 || len(web.Protected) != 2 {
+		t.Errorf("unexpected repository: %+v", web)
+	}
+}
+
+func TestLoadWorkspace_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no repositories": "stale_days: 30\n",
+		"missing path":    "repositories:\n  - name: api\n",
+		"duplicate name":  "repositories:\n  - path: api\n  - path: other\n    name: api\n",
+		"invalid yaml":    "repositories: [",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadWorkspace(writeManifest(t, content)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadWorkspace_Missing(t *testing.T) {
+	_, err := LoadWorkspace(filepath.Join(t.TempDir(), WorkspaceFile))
+	if err == nil || !strings.Contains(err.Error(), "failed to read workspace manifest") {
+		t.Errorf("expected a read error, got %v", err)
+	}
+}
@@ -1,28 +1,54 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"runtime"
+	"time"
 
 	"github.com/onamfc/branch-clean/internal"
 	"github.com/spf13/cobra"
 )
 
 var (
-	dryRun       bool
-	staleDays    int
-	protected    []string
-	mergedOnly   bool
-	staleOnly    bool
-	verbose      bool
-	force        bool
-	assumeYes    bool
-	deleteRemote bool
-	outputFormat string
-	version      = "dev" // Set via ldflags at build time
+	dryRun        bool
+	staleDays     int
+	protected     []string
+	mergedOnly    bool
+	staleOnly     bool
+	verbose       bool
+	force         bool
+	assumeYes     bool
+	deleteRemote  bool
+	outputFormat  string
+	cleanupFormat string
+	templateText  string
+	templateFile  string
+	colorMode     string
+	jobs          int
+	backend       string
+	noCache       bool
+	deepen        int
+	workDir       string
+	archiveMode   string
+	pushArchive   bool
+	bundleDir     string
+	auditLogPath  string
+	lockWait      time.Duration
+	atomicPush    bool
+	forceRemote   bool
+
+	failOnRemoteError bool
+
+	// userConfig is the configuration loaded from ~/.branch-clean.yaml, with
+	// the repository's .branch-clean.yaml merged in once it is opened
+	userConfig *internal.Config
+	// activeCmd is the command being run, to tell which flags were given
+	activeCmd *cobra.Command
+
+	version = "dev" // Set via ldflags at build time
 )
 
 var rootCmd = &cobra.Command{
@@ -30,6 +56,15 @@ var rootCmd = &cobra.Command{
 	Short: "Safely delete merged and stale git branches",
 	Long:  "Interactive tool to clean up merged and stale git branches with safety checks",
 	RunE:  runCleanup,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		activeCmd = cmd
+		if workDir != "" {
+			if err := os.Chdir(workDir); err != nil {
+				return fmt.Errorf("cannot change to %s: %w", workDir, err)
+			}
+		}
+		return internal.ConfigureOutput(internal.ColorMode(colorMode), os.Stdout)
+	},
 }
 
 var listCmd = &cobra.Command{
@@ -53,7 +88,9 @@ func init() {
 		// Config load failed, use defaults
 		config = internal.DefaultConfig()
 	}
+	userConfig = config
 
+	rootCmd.PersistentFlags().StringVarP(&workDir, "directory", "C", "", "Run as if started in this directory")
 	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "d", false, "Show what would be deleted without making changes")
 	rootCmd.PersistentFlags().IntVarP(&staleDays, "stale-days", "s", config.StaleDays, "Days since last commit to consider branch stale")
 	rootCmd.PersistentFlags().StringSliceVarP(&protected, "protect", "p", config.Protected, "Protected branch patterns")
@@ -63,28 +100,135 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
 	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Automatically answer yes to all prompts")
 	rootCmd.PersistentFlags().BoolVar(&deleteRemote, "remote", false, "Also delete branches from remote")
-
-	listCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format: table or json")
+	rootCmd.PersistentFlags().StringVar(&archiveMode, "archive", "", "Move branches to refs/archive/<date>/<name> (--archive or --archive=ref) or to an archive/<name> tag (--archive=tag) instead of deleting them; the mode must be given with =")
+	rootCmd.PersistentFlags().Lookup("archive").NoOptDefVal = string(internal.ArchiveRef)
+	rootCmd.PersistentFlags().BoolVar(&pushArchive, "push-archive", false, "Push archive refs to the remote")
+	rootCmd.PersistentFlags().StringVar(&bundleDir, "bundle-dir", "", "Write a git bundle of each branch to this directory before deleting it")
+	rootCmd.PersistentFlags().StringVar(&auditLogPath, "audit-log", "", "Append cleanup sessions to this audit log instead of .git/branch-clean/audit.log")
+	rootCmd.PersistentFlags().BoolVar(&atomicPush, "atomic-push", false, "With --remote, delete remote branches in an atomic push: all of them or none")
+	rootCmd.PersistentFlags().BoolVar(&forceRemote, "force-remote", false, "With --remote, also delete remote branches with unmerged commits that were not analyzed")
+	rootCmd.PersistentFlags().DurationVar(&lockWait, "wait", 0, "Wait up to this long for another branch-clean session on the repository to finish")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", "auto", "Colorize output: auto, always or never")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "Number of branches to analyze in parallel")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Recompute merge status instead of using the cache in .git/branch-clean")
+	rootCmd.PersistentFlags().IntVar(&deepen, "deepen", 0, "Fetch this many more commits of history into a shallow clone before analysis")
+	rootCmd.PersistentFlags().StringVar(&backend, "backend", "auto", "Git backend: auto, exec (git binary) or go-git (in-process)")
+
+	rootCmd.Flags().StringVar(&cleanupFormat, "format", "", "Report results as json or ndjson, or deleted branches as table, csv, tsv, yaml or markdown")
+	rootCmd.Flags().BoolVar(&failOnRemoteError, "fail-on-remote-error", false, "Exit with code 3 if any remote deletion fails")
+
+	listCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format: table, json, csv, tsv, yaml, markdown or template")
+	listCmd.Flags().StringVar(&templateText, "template", "", "Go template executed for each branch (with --format template)")
+	listCmd.Flags().StringVar(&templateFile, "template-file", "", "Read the output template from a file (with --format template)")
 
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
-func validateGitRepo(path string) error {
-	gitDir := filepath.Join(path, ".git")
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
-		return fmt.Errorf("not a git repository (or any parent up to mount point)\nTry running this command from within a git repository")
+// openRepo opens the git repository in the current working directory. The
+// repository's .branch-clean.yaml is merged into the configuration, so its
+// protected patterns and stale days apply unless given on the command line.
+func openRepo() (*internal.GitRepo, error) {
+	repoPath, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
 	}
-	return nil
+	if root, err := internal.FindRepository(repoPath); err == nil {
+		config, err := repoConfig(activeCmd, root, internal.Config{}, internal.Config{})
+		if err != nil {
+			return nil, err
+		}
+		userConfig, staleDays, protected = config, config.StaleDays, config.Protected
+	}
+	return openRepoAt(repoPath, userConfig)
+}
+
+// openRepoAt opens the git repository at repoPath with the global options and
+// the remote and default branch of config applied
+func openRepoAt(repoPath string, config *internal.Config) (*internal.GitRepo, error) {
+	git, err := internal.NewGitRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	git.SetJobs(jobs)
+	git.SetCache(!noCache)
+	git.SetAtomicPush(atomicPush)
+	if err := git.SetBackend(internal.Backend(backend)); err != nil {
+		return nil, err
+	}
+	if config.Remote != "" {
+		if err := git.SetRemote(config.Remote); err != nil {
+			return nil, err
+		}
+	}
+	if config.DefaultBranch != "" {
+		if err := git.SetDefaultBranch(config.DefaultBranch); err != nil {
+			return nil, err
+		}
+	}
+	if verbose && git.IsBare() {
+		fmt.Fprintln(os.Stderr, "Repository is bare; its branches are deleted directly")
+	}
+	if verbose && (git.IsShallow() || git.IsPartial()) {
+		fmt.Fprintln(os.Stderr, "Repository is a shallow or partial clone; some merge results may be unknown")
+	}
+	if deepen > 0 {
+		if !git.IsShallow() {
+			if verbose {
+				fmt.Fprintln(os.Stderr, "Repository is not shallow, ignoring --deepen")
+			}
+		} else if err := git.Deepen(deepen); err != nil {
+			return nil, err
+		}
+	}
+	return git, nil
 }
 
 func validateFlags() error {
 	if staleDays <= 0 {
 		return fmt.Errorf("stale-days must be positive, got %d", staleDays)
 	}
+	if jobs <= 0 {
+		return fmt.Errorf("jobs must be positive, got %d", jobs)
+	}
+	if deepen < 0 {
+		return fmt.Errorf("deepen must not be negative, got %d", deepen)
+	}
+	if archiveMode != "" {
+		if _, err := internal.ParseArchiveMode(archiveMode); err != nil {
+			return err
+		}
+	} else if pushArchive {
+		return fmt.Errorf("--push-archive requires --archive")
+	}
 	return nil
 }
 
+// newListFormatter builds the formatter for the list command, including
+// user-defined templates given with --template or --template-file
+func newListFormatter() (internal.Formatter, error) {
+	if outputFormat != "template" {
+		if templateText != "" || templateFile != "" {
+			return nil, fmt.Errorf("--template and --template-file require --format template")
+		}
+		return internal.NewFormatter(outputFormat)
+	}
+
+	switch {
+	case templateText != "" && templateFile != "":
+		return nil, fmt.Errorf("--template and --template-file are mutually exclusive")
+	case templateText != "":
+		return internal.NewTemplateFormatter(internal.UnescapeTemplate(templateText))
+	case templateFile != "":
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+		return internal.NewTemplateFormatter(string(data))
+	}
+	return nil, fmt.Errorf("--format template requires --template or --template-file")
+}
+
 func runList(cmd *cobra.Command, args []string) error {
 	// Validate flags
 	if err := validateFlags(); err != nil {
@@ -92,20 +236,12 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	// Validate output format
-	if outputFormat != "table" && outputFormat != "json" {
-		return fmt.Errorf("invalid output format: %s (must be 'table' or 'json')", outputFormat)
-	}
-
-	repoPath, err := os.Getwd()
+	formatter, err := newListFormatter()
 	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
-	}
-
-	if validateErr := validateGitRepo(repoPath); validateErr != nil {
-		return validateErr
+		return err
 	}
 
-	git, err := internal.NewGitRepo(repoPath)
+	git, err := openRepo()
 	if err != nil {
 		return err
 	}
@@ -121,17 +257,10 @@ func runList(cmd *cobra.Command, args []string) error {
 		filtered = internal.FilterBranches(branches, mergedOnly, staleOnly)
 	}
 
-	// Output based on format
-	if outputFormat == "json" {
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		if encodeErr := encoder.Encode(filtered); encodeErr != nil {
-			return fmt.Errorf("failed to encode JSON: %w", encodeErr)
-		}
-	} else {
-		internal.PrintBranches(filtered, false, false)
+	if err := formatter.Format(os.Stdout, filtered); err != nil {
+		return err
 	}
-
+	internal.PrintBranchWarnings(os.Stderr, branches)
 	return nil
 }
 
@@ -141,98 +270,369 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	repoPath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
+	// When a report format is requested, stdout is reserved for the report
+	// and progress messages go to stderr instead
+	var formatter internal.Formatter
+	var reporter *internal.CleanupReporter
+	var out io.Writer = os.Stdout
+	switch cleanupFormat {
+	case "":
+	case "json", "ndjson":
+		r, err := internal.NewCleanupReporter(os.Stdout, cleanupFormat, dryRun)
+		if err != nil {
+			return err
+		}
+		reporter = r
+		out = os.Stderr
+	default:
+		f, err := internal.NewFormatter(cleanupFormat)
+		if err != nil {
+			return err
+		}
+		formatter = f
+		out = os.Stderr
 	}
 
-	if validateErr := validateGitRepo(repoPath); validateErr != nil {
-		return validateErr
+	// finish writes the report for the branches processed so far
+	finish := func(reported []internal.Branch) error {
+		if reporter != nil {
+			return reporter.Close()
+		}
+		if formatter != nil {
+			return formatter.Format(os.Stdout, reported)
+		}
+		return nil
 	}
 
-	git, err := internal.NewGitRepo(repoPath)
+	git, err := openRepo()
 	if err != nil {
 		return err
 	}
+	if err := checkRemoteDeletion(git); err != nil {
+		return err
+	}
+
+	lock, err := lockRepo(git)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock)
+	audit, err := startAudit(git, userConfig)
+	if err != nil {
+		return err
+	}
+	defer closeAudit(audit)
 
 	branches, err := git.ListBranches(staleDays, protected)
 	if err != nil {
 		return fmt.Errorf("failed to list branches: %w", err)
 	}
+	internal.PrintBranchWarnings(os.Stderr, branches)
 
 	filtered := internal.FilterBranches(branches, mergedOnly, staleOnly)
+	if err := recordBranches(audit, without(branches, filtered), ""); err != nil {
+		return err
+	}
 	if len(filtered) == 0 {
-		fmt.Println("No branches to clean up")
-		return nil
+		fmt.Fprintln(out, "No branches to clean up")
+		return finish(nil)
 	}
 
-	selected, err := internal.SelectBranches(filtered)
+	selected, err := selectBranches(out, filtered)
 	if err != nil {
 		return fmt.Errorf("branch selection failed: %w", err)
 	}
+	if err := recordBranches(audit, without(filtered, selected), internal.DecisionNotSelected); err != nil {
+		return err
+	}
 
 	if len(selected) == 0 {
-		fmt.Println("No branches selected")
-		return nil
+		fmt.Fprintln(out, "No branches selected")
+		return finish(nil)
 	}
 
 	// Skip confirmation if force or assumeYes flag is set
-	if !force && !assumeYes && !internal.ConfirmDeletion(selected, dryRun) {
-		fmt.Println("Canceled")
-		return nil
+	if !force && !assumeYes && !internal.ConfirmAction(out, cleanupAction(), branchNames(selected), dryRun) {
+		fmt.Fprintln(out, "Canceled")
+		if err := recordBranches(audit, selected, internal.DecisionDeclined); err != nil {
+			return err
+		}
+		return finish(nil)
 	}
 
 	if dryRun {
-		fmt.Println("\n[DRY RUN] Would delete:")
+		fmt.Fprintf(out, "\n[DRY RUN] Would %s:\n", cleanupAction())
 		for _, b := range selected {
-			fmt.Printf("  - %s", b.Name)
+			fmt.Fprintf(out, "  - %s", b.Name)
+			result := internal.CleanupResult{
+				Branch: b.Name,
+				Tip:    b.Tip,
+				Local:  internal.DeletionResult{Outcome: wouldOutcome()},
+			}
+			if archiveMode != "" {
+				result.Archive = internal.ArchiveRefName(b.Name, internal.ArchiveMode(archiveMode), time.Now())
+				fmt.Fprintf(out, " to %s", result.Archive)
+			}
 			if deleteRemote {
-				fmt.Printf(" (local and remote)")
+				fmt.Fprintf(out, " (local and remote)")
+				result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeWouldDelete}
+			}
+			fmt.Fprintln(out)
+			if err := audit.RecordResult(b, cleanupAction(), result); err != nil {
+				return err
+			}
+			if reporter != nil {
+				if err := reporter.Add(result); err != nil {
+					return err
+				}
 			}
-			fmt.Println()
 		}
-		return nil
+		return finish(selected)
+	}
+
+	bundles, err := newBundleSession(git)
+	if err != nil {
+		return err
 	}
 
 	var hasErrors bool
-	var successCount int
-	for _, branch := range selected {
-		if verbose {
-			fmt.Printf("Deleting branch: %s\n", branch.Name)
+	var remoteFailures int
+	var deleted []internal.Branch
+	for i, result := range cleanupBranches(git, selected, out, bundles) {
+		branch := selected[i]
+		if err := audit.RecordResult(branch, cleanupAction(), result); err != nil {
+			return err
 		}
-
-		// Delete local branch
-		if err := git.DeleteBranch(branch.Name); err != nil {
-			fmt.Fprintf(os.Stderr, "✗ Failed to delete local branch %s: %v\n", branch.Name, err)
+		if result.Local.Outcome == internal.OutcomeDeleted {
+			deleted = append(deleted, branch)
+		} else {
 			hasErrors = true
-			continue
 		}
-		fmt.Printf("✓ Deleted local branch %s\n", branch.Name)
-		successCount++
-
-		// Delete remote branch if flag is set
-		if deleteRemote {
-			if err := git.DeleteRemoteBranch(branch.Name); err != nil {
-				fmt.Fprintf(os.Stderr, "⚠ Failed to delete remote branch %s: %v\n", branch.Name, err)
-				// Don't mark as error since local deletion succeeded
-			} else {
-				fmt.Printf("✓ Deleted remote branch %s\n", branch.Name)
+		if result.Remote != nil && remoteFailed(result.Remote.Outcome) {
+			remoteFailures++
+		}
+		if reporter != nil {
+			if err := reporter.Add(result); err != nil {
+				return err
 			}
 		}
 	}
 
-	fmt.Printf("\nDeleted %d of %d branches\n", successCount, len(selected))
+	fmt.Fprintf(out, "\n%s %d of %d branches\n", cleanupPastTense(), len(deleted), len(selected))
+
+	if err := finish(deleted); err != nil {
+		return err
+	}
 
 	if hasErrors {
 		return fmt.Errorf("some branches failed to delete")
 	}
+	if failOnRemoteError && remoteFailures > 0 {
+		return internal.ErrRemoteDeleteFailed
+	}
 	return nil
 }
 
+// remoteFailed reports whether a remote outcome counts as a remote failure for
+// --fail-on-remote-error
+func remoteFailed(outcome string) bool {
+	return outcome == internal.OutcomeFailed || outcome == internal.OutcomeChanged || outcome == internal.OutcomeRefused
+}
+
+// cleanupAction names what cleanup does to the selected branches
+func cleanupAction() string {
+	if archiveMode != "" {
+		return internal.DecisionArchive
+	}
+	return internal.DecisionDelete
+}
+
+// wouldOutcome is the dry-run outcome of the local cleanup step
+func wouldOutcome() string {
+	if archiveMode != "" {
+		return internal.OutcomeWouldArchive
+	}
+	return internal.OutcomeWouldDelete
+}
+
+// cleanupPastTense is cleanupAction for summaries
+func cleanupPastTense() string {
+	if archiveMode != "" {
+		return "Archived"
+	}
+	return "Deleted"
+}
+
+func branchNames(branches []internal.Branch) []string {
+	names := make([]string, len(branches))
+	for i, b := range branches {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// selectBranches asks on out which of branches to delete. With --force or
+// --yes and no terminal to prompt on, as when run from cron, all of them are
+// selected.
+func selectBranches(out io.Writer, branches []internal.Branch) ([]internal.Branch, error) {
+	if (force || assumeYes) && !internal.Interactive() {
+		return branches, nil
+	}
+	return internal.SelectBranches(out, branches)
+}
+
+// checkRemoteDeletion rejects --remote in a bare repository, whose branches
+// are the ones on the server
+func checkRemoteDeletion(git *internal.GitRepo) error {
+	if deleteRemote && git.IsBare() {
+		return fmt.Errorf("--remote cannot be used in a bare repository: %s\nIts branches are deleted directly", git.Path())
+	}
+	return nil
+}
+
+// newBundleSession starts a bundle session for git with --bundle-dir, and
+// returns nil without it
+func newBundleSession(git *internal.GitRepo) (*internal.BundleSession, error) {
+	if bundleDir == "" {
+		return nil, nil
+	}
+	return git.NewBundleSession(bundleDir, time.Now())
+}
+
+// deleteBranch deletes a branch locally and, with --remote, on the remote,
+// printing progress to out and returning the outcome of both steps. With
+// bundles, the branch is only deleted once its bundle is written.
+func deleteBranch(git *internal.GitRepo, branch internal.Branch, out io.Writer, bundles *internal.BundleSession) internal.CleanupResult {
+	result := internal.CleanupResult{Branch: branch.Name, Tip: branch.Tip}
+
+	if bundles != nil {
+		path, err := bundles.Write(git, branch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Failed to bundle branch %s, not deleting it: %v\n", branch.Name, err)
+			result.Local = internal.DeletionResult{Outcome: internal.OutcomeFailed, Error: err.Error()}
+			if deleteRemote {
+				result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeSkipped}
+			}
+			return result
+		}
+		fmt.Fprintf(out, "✓ Bundled branch %s to %s\n", branch.Name, path)
+	}
+
+	// Delete or archive local branch
+	var err error
+	if archiveMode != "" {
+		result.Archive, err = git.ArchiveBranch(branch.Name, branch.Tip, internal.ArchiveMode(archiveMode), time.Now())
+	} else {
+		err = git.DeleteBranch(branch.Name, branch.Tip)
+	}
+	if errors.Is(err, internal.ErrBranchChanged) {
+		fmt.Fprintf(os.Stderr, "⚠ Kept local branch %s: %v\n", branch.Name, err)
+		result.Local = internal.DeletionResult{Outcome: internal.OutcomeChanged, Error: err.Error()}
+		if deleteRemote {
+			result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeSkipped}
+		}
+		return result
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to %s local branch %s: %v\n", cleanupAction(), branch.Name, err)
+		result.Local = internal.DeletionResult{Outcome: internal.OutcomeFailed, Error: err.Error()}
+		if deleteRemote {
+			result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeSkipped}
+		}
+		return result
+	}
+	if result.Archive != "" {
+		fmt.Fprintf(out, "✓ Archived local branch %s to %s\n", branch.Name, result.Archive)
+	} else {
+		fmt.Fprintf(out, "✓ Deleted local branch %s\n", branch.Name)
+	}
+	result.Local = internal.DeletionResult{Outcome: internal.OutcomeDeleted}
+
+	// The remote branch is only deleted once its archive is on the remote
+	if pushArchive {
+		if err := git.PushArchive(result.Archive); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Failed to push %s: %v\n", result.Archive, err)
+			if deleteRemote {
+				result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeSkipped, Error: err.Error()}
+			}
+			return result
+		}
+		fmt.Fprintf(out, "✓ Pushed %s\n", result.Archive)
+	}
+
+	return result
+}
+
+// cleanupBranches deletes or archives each branch locally, then deletes the
+// remote branches of all that succeeded in a single push. A remote branch is
+// only deleted if its tip on the remote is the analyzed tip or is merged,
+// unless --force-remote is set.
+func cleanupBranches(git *internal.GitRepo, branches []internal.Branch, out io.Writer, bundles *internal.BundleSession) []internal.CleanupResult {
+	results := make([]internal.CleanupResult, len(branches))
+	var pending []int
+	for i, branch := range branches {
+		if verbose {
+			fmt.Fprintf(out, "Deleting branch: %s\n", branch.Name)
+		}
+		results[i] = deleteBranch(git, branch, out, bundles)
+		if deleteRemote && results[i].Remote == nil {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return results
+	}
+
+	tips, err := git.RemoteBranchTips()
+	var deletions []internal.RemoteDeletion
+	var verified []int
+	for _, i := range pending {
+		branch := branches[i]
+		remoteTip, found := tips[branch.Name]
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Failed to delete remote branch %s: %v\n", branch.Name, err)
+			results[i].Remote = &internal.DeletionResult{Outcome: internal.OutcomeFailed, Error: err.Error()}
+			continue
+		}
+		if !found {
+			results[i].Remote = &internal.DeletionResult{Outcome: internal.OutcomeSkipped, Error: "not found on the remote"}
+			continue
+		}
+		if checkErr := git.CheckRemoteBranch(branch, remoteTip); checkErr != nil && !forceRemote {
+			fmt.Fprintf(os.Stderr, "⚠ Kept remote branch %s: %v (use --force-remote to delete it anyway)\n", branch.Name, checkErr)
+			results[i].Remote = &internal.DeletionResult{Outcome: internal.OutcomeRefused, Error: checkErr.Error()}
+			continue
+		}
+		// The push only deletes the branch if it still has the verified tip
+		deletions = append(deletions, internal.RemoteDeletion{Name: branch.Name, ExpectedTip: remoteTip})
+		verified = append(verified, i)
+	}
+
+	for j, err := range git.DeleteRemoteBranches(deletions) {
+		name := deletions[j].Name
+		result := &results[verified[j]]
+		if errors.Is(err, internal.ErrBranchChanged) {
+			fmt.Fprintf(os.Stderr, "⚠ Kept remote branch %s: %v\n", name, err)
+			result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeChanged, Error: err.Error()}
+		} else if errors.Is(err, internal.ErrRemoteBranchNotFound) {
+			result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeSkipped, Error: "not found on the remote"}
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Failed to delete remote branch %s: %v\n", name, err)
+			// Don't mark as error since local deletion succeeded
+			result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeFailed, Error: err.Error()}
+		} else {
+			fmt.Fprintf(out, "✓ Deleted remote branch %s\n", name)
+			result.Remote = &internal.DeletionResult{Outcome: internal.OutcomeDeleted}
+		}
+	}
+	return results
+}
+
 const (
 	exitSuccess         = 0
 	exitError           = 1
 	exitProtectedBranch = 2
+	exitRemoteFailure   = 3
 )
 
 func main() {
@@ -242,6 +642,9 @@ func main() {
 		if errors.As(err, &protectedErr) {
 			os.Exit(exitProtectedBranch)
 		}
+		if errors.Is(err, internal.ErrRemoteDeleteFailed) {
+			os.Exit(exitRemoteFailure)
+		}
 		if errors.Is(err, internal.ErrProtectedBranch) ||
 			errors.Is(err, internal.ErrCurrentBranch) ||
 			errors.Is(err, internal.ErrDefaultBranch) {
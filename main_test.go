@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/onamfc/branch-clean/internal"
+)
+
+// setupRepo creates a repository with a master, keep/one and feature branch
+// and the given .branch-clean.yaml
+func setupRepo(t *testing.T, config string) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "test.txt"), []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, _ := repo.Worktree()
+	w.Add("test.txt")
+	hash, err := w.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@test.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	for _, name := range []string{"keep/one", "feature"} {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, internal.RepoConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(cwd) })
+	return dir
+}
+
+func TestList_RepoConfigProtected(t *testing.T) {
+	dir := setupRepo(t, "protected:\n  - master\n  - keep/*\n")
+
+	output := captureStdout(t, func() {
+		rootCmd.SetArgs([]string{"-C", dir, "list", "--format", "json"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+	})
+
+	var branches []internal.Branch
+	if err := json.Unmarshal(output, &branches); err != nil {
+		t.Fatalf("failed to parse output: %v\n%s", err, output)
+	}
+	protected := make(map[string]bool)
+	for _, b := range branches {
+		protected[b.Name] = b.Protected
+	}
+	if !protected["keep/one"] {
+		t.Error("keep/one: expected the repository config to protect it")
+	}
+	if protected["feature"] {
+		t.Error("feature: expected it not to be protected")
+	}
+}
+
+func TestList_RepoConfigInvalid(t *testing.T) {
+	dir := setupRepo(t, "stale_days: -1\n")
+
+	captureStdout(t, func() {
+		rootCmd.SetArgs([]string{"-C", dir, "list", "--format", "json"})
+		if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "stale_days") {
+			t.Errorf("expected the repository's stale_days to be rejected, got %v", err)
+		}
+	})
+}
+
+// captureStdout returns what fn writes to standard output
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestArchiveFlag(t *testing.T) {
+	t.Cleanup(func() {
+		archiveMode = ""
+		rootCmd.PersistentFlags().Lookup("archive").Changed = false
+	})
+
+	tests := []struct {
+		args     []string
+		wantMode string
+		wantArgs []string
+	}{
+		{[]string{"--archive"}, "ref", nil},
+		{[]string{"--archive=tag"}, "tag", nil},
+		// Without =, the mode is not part of the flag
+		{[]string{"--archive", "tag"}, "ref", []string{"tag"}},
+	}
+	for _, tt := range tests {
+		archiveMode = ""
+		flags := rootCmd.PersistentFlags()
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatalf("%v: parse failed: %v", tt.args, err)
+		}
+		if archiveMode != tt.wantMode {
+			t.Errorf("%v: archive mode = %q, want %q", tt.args, archiveMode, tt.wantMode)
+		}
+		if got := flags.Args(); len(got) != len(tt.wantArgs) || (len(got) > 0 && got[0] != tt.wantArgs[0]) {
+			t.Errorf("%v: arguments = %v, want %v", tt.args, got, tt.wantArgs)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var planOutput string
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Write a reviewable plan of the branches to clean up",
+	Long: "Write a plan listing every cleanup candidate with its tip. Review or edit the plan, " +
+		"then run 'branch-clean apply <plan>' to delete exactly those branches.",
+	Args: cobra.NoArgs,
+	RunE: runPlan,
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan>",
+	Short: "Clean up the branches of a plan, refusing any that changed since planning",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runApply,
+}
+
+func init() {
+	planCmd.Flags().StringVarP(&planOutput, "output", "o", "-", "Write the plan to this file (- for stdout)")
+
+	rootCmd.AddCommand(planCmd, applyCmd)
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	if err := validateFlags(); err != nil {
+		return err
+	}
+
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+	if err := checkRemoteDeletion(git); err != nil {
+		return err
+	}
+
+	branches, err := git.ListBranches(staleDays, protected)
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+	internal.PrintBranchWarnings(os.Stderr, branches)
+
+	candidates := internal.FilterBranches(branches, mergedOnly, staleOnly)
+	plan := git.NewPlan(candidates, deleteRemote, internal.ArchiveMode(archiveMode), time.Now())
+
+	if planOutput == "-" {
+		return internal.WritePlan(os.Stdout, plan)
+	}
+	file, err := os.Create(planOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create plan: %w", err)
+	}
+	if err := internal.WritePlan(file, plan); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Planned %s. Review %s, then run: branch-clean apply %s\n",
+		internal.PluralBranches(len(plan.Branches)), planOutput, planOutput)
+	return nil
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	if err := validateFlags(); err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("remote") || cmd.Flags().Changed("archive") {
+		return fmt.Errorf("--remote and --archive are taken from the plan; create a new plan to change them")
+	}
+
+	plan, err := internal.LoadPlan(args[0])
+	if err != nil {
+		return err
+	}
+	deleteRemote = plan.DeleteRemote
+	archiveMode = string(plan.Archive)
+
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+	if err := plan.CheckRepository(git); err != nil {
+		return err
+	}
+	if err := checkRemoteDeletion(git); err != nil {
+		return err
+	}
+
+	lock, err := lockRepo(git)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock)
+	audit, err := startAudit(git, userConfig)
+	if err != nil {
+		return err
+	}
+	defer closeAudit(audit)
+
+	branches, err := git.ListBranches(staleDays, protected)
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+	current := make(map[string]internal.Branch, len(branches))
+	for _, b := range branches {
+		current[b.Name] = b
+	}
+
+	// Only branches that are exactly as planned are cleaned up
+	var ready []internal.Branch
+	var refused int
+	for _, planned := range plan.Branches {
+		b, ok := current[planned.Name]
+		var reason string
+		switch {
+		case !ok:
+			b = internal.Branch{Name: planned.Name, Tip: planned.Tip}
+			reason = "no longer exists"
+		case b.Tip != planned.Tip:
+			reason = fmt.Sprintf("tip moved since planning (planned %.8s, now %.8s)", planned.Tip, b.Tip)
+		case b.Protected:
+			reason = "is protected"
+		default:
+			ready = append(ready, b)
+			continue
+		}
+
+		refused++
+		fmt.Fprintf(os.Stderr, "✗ Refusing %s: %s\n", planned.Name, reason)
+		result := internal.CleanupResult{Local: internal.DeletionResult{Outcome: internal.OutcomeSkipped, Error: reason}}
+		if err := audit.RecordResult(b, internal.DecisionRefused, result); err != nil {
+			return err
+		}
+	}
+
+	if len(ready) == 0 {
+		fmt.Println("No planned branches to clean up")
+	} else if !force && !assumeYes && !internal.ConfirmAction(os.Stdout, cleanupAction(), branchNames(ready), dryRun) {
+		fmt.Println("Canceled")
+		return recordBranches(audit, ready, internal.DecisionDeclined)
+	} else if dryRun {
+		for _, b := range ready {
+			fmt.Printf("[DRY RUN] Would %s %s\n", cleanupAction(), b.Name)
+			result := internal.CleanupResult{Branch: b.Name, Tip: b.Tip, Local: internal.DeletionResult{Outcome: wouldOutcome()}}
+			if err := audit.RecordResult(b, cleanupAction(), result); err != nil {
+				return err
+			}
+		}
+	} else {
+		bundles, err := newBundleSession(git)
+		if err != nil {
+			return err
+		}
+		var deleted int
+		for i, result := range cleanupBranches(git, ready, os.Stdout, bundles) {
+			if err := audit.RecordResult(ready[i], cleanupAction(), result); err != nil {
+				return err
+			}
+			if result.Local.Outcome == internal.OutcomeDeleted {
+				deleted++
+			}
+		}
+		fmt.Printf("\n%s %d of %d planned branches\n", cleanupPastTense(), deleted, len(plan.Branches))
+		if deleted < len(ready) {
+			return fmt.Errorf("some branches failed to delete")
+		}
+	}
+
+	if refused > 0 {
+		return fmt.Errorf("refused %s that changed since planning", internal.PluralBranches(refused))
+	}
+	return nil
+}
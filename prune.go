@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var pruneOffline bool
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove remote-tracking refs of branches deleted on the remote",
+	Long: "Remove remote-tracking refs, such as origin/feature-x, whose branch no longer exists on the remote. " +
+		"The remote is listed with git ls-remote, or with --offline, the branches recorded in FETCH_HEAD by the last fetch are used.",
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+func init() {
+	pruneCmd.Flags().BoolVar(&pruneOffline, "offline", false, "Use FETCH_HEAD of the last fetch instead of contacting the remote")
+
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+	lock, err := lockRepo(git)
+	if err != nil {
+		return err
+	}
+	defer releaseLock(lock)
+
+	var stale []internal.TrackingRef
+	if pruneOffline {
+		stale, err = git.OfflineStaleTrackingRefs()
+	} else {
+		var tips map[string]string
+		if tips, err = git.RemoteBranchTips(); err == nil {
+			stale, err = git.StaleTrackingRefs(tips)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		fmt.Println("No stale remote-tracking refs")
+		return nil
+	}
+
+	names := make([]string, len(stale))
+	for i, ref := range stale {
+		names[i] = ref.Name
+	}
+	if !force && !assumeYes && !internal.ConfirmAction(os.Stdout, internal.DecisionPrune, names, dryRun) {
+		fmt.Println("Canceled")
+		return nil
+	}
+
+	audit, err := startAudit(git, userConfig)
+	if err != nil {
+		return err
+	}
+	defer closeAudit(audit)
+
+	if dryRun {
+		for _, ref := range stale {
+			fmt.Printf("[DRY RUN] Would prune %s\n", ref.Name)
+			result := internal.DeletionResult{Outcome: internal.OutcomeWouldDelete}
+			if err := audit.RecordAction(ref.Name, ref.Tip, internal.DecisionPrune, "", result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var failed bool
+	for _, ref := range stale {
+		err := git.DeleteTrackingRef(ref)
+		if auditErr := audit.RecordAction(ref.Name, ref.Tip, internal.DecisionPrune, "", outcome(err, internal.OutcomeDeleted)); auditErr != nil {
+			return auditErr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ Pruned %s\n", ref.Name)
+	}
+	if failed {
+		return fmt.Errorf("some remote-tracking refs could not be pruned")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportHTML  string
+	reportTitle string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate a self-contained HTML branch report",
+	RunE:  runReport,
+}
+
+func init() {
+	reportCmd.Flags().StringVar(&reportHTML, "html", "", "Write the HTML report to this file (- for stdout)")
+	reportCmd.Flags().StringVar(&reportTitle, "title", "", "Report title (default: branch report for the repository)")
+	_ = reportCmd.MarkFlagRequired("html")
+
+	rootCmd.AddCommand(reportCmd)
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	if err := validateFlags(); err != nil {
+		return err
+	}
+
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	branches, err := git.ListBranches(staleDays, protected)
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+	internal.PrintBranchWarnings(os.Stderr, branches)
+
+	name := filepath.Base(git.Path())
+	title := reportTitle
+	if title == "" {
+		title = fmt.Sprintf("Branch report: %s", name)
+	}
+
+	report := internal.NewHTMLReport(title, []internal.RepoReport{
+		{Name: name, Path: git.Path(), Branches: branches},
+	}, time.Now())
+
+	return writeHTMLReport(reportHTML, report)
+}
+
+// writeHTMLReport renders report to path, or to stdout if path is "-"
+func writeHTMLReport(path string, report internal.HTMLReport) error {
+	return writeReportFile(path, report, internal.WriteHTMLReport)
+}
+
+// writeJSONReport writes report as JSON to path, or to stdout if path is "-"
+func writeJSONReport(path string, report internal.HTMLReport) error {
+	return writeReportFile(path, report, internal.WriteReportJSON)
+}
+
+func writeReportFile(path string, report internal.HTMLReport, write func(io.Writer, internal.HTMLReport) error) error {
+	if path == "-" {
+		return write(os.Stdout, report)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+
+	if err := write(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote report to %s\n", path)
+	return nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanMaxDepth int
+	scanIgnore   []string
+	scanFormat   string
+	scanCleanup  bool
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan <dir>",
+	Short: "List or clean up the branches of all repositories under a directory",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runScan,
+}
+
+func init() {
+	scanCmd.Flags().IntVar(&scanMaxDepth, "max-depth", 3, "How many directory levels below <dir> to search for repositories")
+	scanCmd.Flags().StringSliceVar(&scanIgnore, "ignore", internal.DefaultScanIgnore, "Directory name or path patterns to skip")
+	scanCmd.Flags().StringVar(&scanFormat, "format", "table", "Output format: table or json")
+	scanCmd.Flags().BoolVar(&scanCleanup, "cleanup", false, "Clean up branches repository by repository instead of listing them")
+
+	rootCmd.AddCommand(scanCmd)
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	if err := validateFlags(); err != nil {
+		return err
+	}
+	if scanMaxDepth < 0 {
+		return fmt.Errorf("max-depth must not be negative, got %d", scanMaxDepth)
+	}
+	if scanFormat != "table" && scanFormat != "json" {
+		return fmt.Errorf("invalid output format: %s (must be 'table' or 'json')", scanFormat)
+	}
+	if scanCleanup && scanFormat != "table" {
+		return fmt.Errorf("--cleanup cannot be combined with --format %s", scanFormat)
+	}
+
+	paths, err := internal.DiscoverRepositories(args[0], scanMaxDepth, scanIgnore)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "No git repositories found under %s\n", args[0])
+		return nil
+	}
+
+	repos := internal.NameRepositories(args[0], paths)
+	open := func(repo internal.RepoReport) (*internal.GitRepo, *internal.Config, error) {
+		config, err := repoConfig(cmd, repo.Path, internal.Config{}, internal.Config{})
+		if err != nil {
+			return nil, nil, err
+		}
+		git, err := openRepoAt(repo.Path, config)
+		return git, config, err
+	}
+
+	failed := analyzeRepositories(repos, open)
+	if scanCleanup {
+		if err := cleanupRepositories(repos, open); err != nil {
+			return err
+		}
+	} else if err := writeRepositories(repos, scanFormat); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories could not be scanned", failed, len(repos))
+	}
+	return nil
+}
+
+// repoOpener opens a repository of a multi-repository command together with
+// its effective configuration
+type repoOpener func(repo internal.RepoReport) (*internal.GitRepo, *internal.Config, error)
+
+// analyzeRepositories lists the branches of all repositories in parallel,
+// applies the --merged-only and --stale-only filters and returns the number
+// of repositories that failed
+func analyzeRepositories(repos []internal.RepoReport, open repoOpener) int {
+	// Repositories are analyzed in parallel, so each one uses a single worker
+	internal.AnalyzeRepositories(repos, jobs, time.Now(), func(repo internal.RepoReport) ([]internal.Branch, error) {
+		git, config, err := open(repo)
+		if err != nil {
+			return nil, err
+		}
+		git.SetJobs(1)
+		return git.ListBranches(config.StaleDays, config.Protected)
+	})
+
+	failed := 0
+	for i := range repos {
+		if repos[i].Error != "" {
+			failed++
+		}
+		if mergedOnly || staleOnly {
+			repos[i].Branches = internal.FilterBranches(repos[i].Branches, mergedOnly, staleOnly)
+		}
+	}
+	return failed
+}
+
+// writeRepositories writes the branches of each repository as grouped tables or JSON
+func writeRepositories(repos []internal.RepoReport, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(repos); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return nil
+	}
+	internal.PrintRepositories(os.Stdout, repos)
+	return nil
+}
+
+// repoConfig returns the configuration for the repository at path, applying
+// in increasing order of precedence: the user configuration, defaults, the
+// repository's .branch-clean.yaml, override, and flags given on the command line
+func repoConfig(cmd *cobra.Command, path string, defaults, override internal.Config) (*internal.Config, error) {
+	config, err := internal.LoadRepoConfig(path, userConfig.Merge(defaults))
+	if err != nil {
+		return nil, err
+	}
+
+	var flags internal.Config
+	if cmd.Flags().Changed("stale-days") {
+		flags.StaleDays = staleDays
+	}
+	if cmd.Flags().Changed("protect") {
+		flags.Protected = protected
+	}
+	config = config.Merge(override).Merge(flags)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// cleanupRepositories runs the interactive cleanup for each repository in
+// turn. A repository that fails does not stop the cleanup of the others.
+func cleanupRepositories(repos []internal.RepoReport, open repoOpener) error {
+	var candidates, selectedTotal, deletedTotal, failedTotal, failedRepos int
+	for _, repo := range repos {
+		if repo.Error != "" {
+			continue
+		}
+		branches := internal.FilterBranches(repo.Branches, mergedOnly, staleOnly)
+		if len(branches) == 0 {
+			continue
+		}
+		candidates += len(branches)
+
+		fmt.Printf("\n== %s\n", repo.Name)
+		selected, deleted, failed, err := cleanupRepository(repo, branches, open)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", repo.Name, err)
+			failedRepos++
+		}
+		selectedTotal += selected
+		deletedTotal += deleted
+		failedTotal += failed
+	}
+
+	if candidates == 0 {
+		fmt.Println("No branches to clean up")
+		return nil
+	}
+	if selectedTotal > 0 && !dryRun {
+		fmt.Printf("\n%s %d of %d branches\n", cleanupPastTense(), deletedTotal, selectedTotal)
+	}
+	if failedTotal > 0 || failedRepos > 0 {
+		return fmt.Errorf("some branches failed to delete")
+	}
+	return nil
+}
+
+// cleanupRepository runs the interactive cleanup of the candidate branches of
+// one repository, recording the session in its audit log, and returns how
+// many branches were selected, deleted and failed. An error means the
+// repository could not be cleaned up at all.
+func cleanupRepository(repo internal.RepoReport, branches []internal.Branch, open repoOpener) (selectedCount, deleted, failed int, err error) {
+	git, config, err := open(repo)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err := checkRemoteDeletion(git); err != nil {
+		return 0, 0, 0, err
+	}
+	lock, err := lockRepo(git)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer releaseLock(lock)
+	audit, err := startAudit(git, config)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer closeAudit(audit)
+	if err := recordBranches(audit, without(repo.Branches, branches), ""); err != nil {
+		return 0, 0, 0, err
+	}
+
+	selected, err := selectBranches(os.Stdout, branches)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("branch selection failed: %w", err)
+	}
+	if err := recordBranches(audit, without(branches, selected), internal.DecisionNotSelected); err != nil {
+		return 0, 0, 0, err
+	}
+	if len(selected) == 0 {
+		fmt.Println("No branches selected")
+		return 0, 0, 0, nil
+	}
+	if !force && !assumeYes && !internal.ConfirmAction(os.Stdout, cleanupAction(), branchNames(selected), dryRun) {
+		fmt.Println("Skipped")
+		return 0, 0, 0, recordBranches(audit, selected, internal.DecisionDeclined)
+	}
+
+	if dryRun {
+		for _, b := range selected {
+			fmt.Printf("[DRY RUN] Would %s %s\n", cleanupAction(), b.Name)
+			result := internal.CleanupResult{Branch: b.Name, Tip: b.Tip, Local: internal.DeletionResult{Outcome: wouldOutcome()}}
+			if err := audit.RecordResult(b, cleanupAction(), result); err != nil {
+				return len(selected), 0, 0, err
+			}
+		}
+		return len(selected), 0, 0, nil
+	}
+
+	bundles, err := newBundleSession(git)
+	if err != nil {
+		return len(selected), 0, len(selected), err
+	}
+	results := cleanupBranches(git, selected, os.Stdout, bundles)
+	for _, result := range results {
+		if result.Local.Outcome == internal.OutcomeDeleted {
+			deleted++
+		} else {
+			failed++
+		}
+	}
+	for i, result := range results {
+		if err := audit.RecordResult(selected[i], cleanupAction(), result); err != nil {
+			return len(selected), deleted, failed, err
+		}
+	}
+	return len(selected), deleted, failed, nil
+}
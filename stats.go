@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var statsFormat string
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show branch health statistics for the repository",
+	RunE:  runStats,
+}
+
+func init() {
+	statsCmd.Flags().StringVar(&statsFormat, "format", "table", "Output format: table or json")
+
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if err := validateFlags(); err != nil {
+		return err
+	}
+
+	if statsFormat != "table" && statsFormat != "json" {
+		return fmt.Errorf("invalid output format: %s (must be 'table' or 'json')", statsFormat)
+	}
+
+	git, err := openRepo()
+	if err != nil {
+		return err
+	}
+
+	branches, err := git.ListBranches(staleDays, protected)
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+	internal.PrintBranchWarnings(os.Stderr, branches)
+
+	stats := internal.ComputeStats(branches, time.Now())
+	if statsFormat == "json" {
+		return internal.WriteStatsJSON(os.Stdout, stats)
+	}
+
+	internal.PrintStats(os.Stdout, stats)
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onamfc/branch-clean/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	workspaceManifest string
+	workspaceFormat   string
+	workspaceHTML     string
+	workspaceJSON     string
+	workspaceTitle    string
+)
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Run branch-clean across the repositories of a workspace manifest",
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the branches of all workspace repositories",
+	Args:  cobra.NoArgs,
+	RunE:  runWorkspaceList,
+}
+
+var workspaceCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Clean up branches repository by repository",
+	Args:  cobra.NoArgs,
+	RunE:  runWorkspaceClean,
+}
+
+var workspaceReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Write a combined HTML and/or JSON report for all workspace repositories",
+	Args:  cobra.NoArgs,
+	RunE:  runWorkspaceReport,
+}
+
+func init() {
+	workspaceCmd.PersistentFlags().StringVar(&workspaceManifest, "manifest", internal.WorkspaceFile, "Workspace manifest file")
+
+	workspaceListCmd.Flags().StringVar(&workspaceFormat, "format", "table", "Output format: table or json")
+
+	workspaceReportCmd.Flags().StringVar(&workspaceHTML, "html", "", "Write the HTML report to this file (- for stdout)")
+	workspaceReportCmd.Flags().StringVar(&workspaceJSON, "json", "", "Write the JSON report to this file (- for stdout)")
+	workspaceReportCmd.Flags().StringVar(&workspaceTitle, "title", "Workspace branch report", "Report title")
+
+	workspaceCmd.AddCommand(workspaceListCmd, workspaceCleanCmd, workspaceReportCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
+
+// loadWorkspace reads the manifest and returns a report for each of its
+// repositories, together with an opener applying the per-repository overrides
+func loadWorkspace(cmd *cobra.Command) ([]internal.RepoReport, repoOpener, error) {
+	if err := validateFlags(); err != nil {
+		return nil, nil, err
+	}
+
+	workspace, err := internal.LoadWorkspace(workspaceManifest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repos := make([]internal.RepoReport, len(workspace.Repositories))
+	overrides := make(map[string]internal.Config)
+	for i, repo := range workspace.Repositories {
+		repos[i] = internal.RepoReport{Name: repo.Name, Path: repo.Path}
+		overrides[repo.Name] = repo.Config
+	}
+
+	open := func(repo internal.RepoReport) (*internal.GitRepo, *internal.Config, error) {
+		config, err := repoConfig(cmd, repo.Path, workspace.Config, overrides[repo.Name])
+		if err != nil {
+			return nil, nil, err
+		}
+		git, err := openRepoAt(repo.Path, config)
+		return git, config, err
+	}
+	return repos, open, nil
+}
+
+func runWorkspaceList(cmd *cobra.Command, args []string) error {
+	if workspaceFormat != "table" && workspaceFormat != "json" {
+		return fmt.Errorf("invalid output format: %s (must be 'table' or 'json')", workspaceFormat)
+	}
+
+	repos, open, err := loadWorkspace(cmd)
+	if err != nil {
+		return err
+	}
+
+	failed := analyzeRepositories(repos, open)
+	if err := writeRepositories(repos, workspaceFormat); err != nil {
+		return err
+	}
+	return workspaceFailures(failed, len(repos))
+}
+
+func runWorkspaceClean(cmd *cobra.Command, args []string) error {
+	repos, open, err := loadWorkspace(cmd)
+	if err != nil {
+		return err
+	}
+
+	failed := analyzeRepositories(repos, open)
+	for _, repo := range repos {
+		if repo.Error != "" {
+			fmt.Fprintf(os.Stderr, "✗ %s: %s\n", repo.Name, repo.Error)
+		}
+	}
+	if err := cleanupRepositories(repos, open); err != nil {
+		return err
+	}
+	return workspaceFailures(failed, len(repos))
+}
+
+func runWorkspaceReport(cmd *cobra.Command, args []string) error {
+	if workspaceHTML == "" && workspaceJSON == "" {
+		return fmt.Errorf("at least one of --html or --json is required")
+	}
+	if workspaceHTML == "-" && workspaceJSON == "-" {
+		return fmt.Errorf("--html and --json cannot both write to stdout")
+	}
+
+	repos, open, err := loadWorkspace(cmd)
+	if err != nil {
+		return err
+	}
+
+	failed := analyzeRepositories(repos, open)
+	report := internal.NewHTMLReport(workspaceTitle, repos, time.Now())
+	if workspaceHTML != "" {
+		if err := writeHTMLReport(workspaceHTML, report); err != nil {
+			return err
+		}
+	}
+	if workspaceJSON != "" {
+		if err := writeJSONReport(workspaceJSON, report); err != nil {
+			return err
+		}
+	}
+	return workspaceFailures(failed, len(repos))
+}
+
+func workspaceFailures(failed, total int) error {
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories could not be analyzed", failed, total)
+	}
+	return nil
+}